@@ -1,30 +1,219 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/michaelprice232/ecr-image-checker/internal/checker"
 )
 
+const (
+	appName        = "ecr-image-checker"
+	defaultCommand = "check"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(checker.Options) error
+
+	// checksRegistries is set for the commands which call the registries, and so accept a concurrency
+	checksRegistries bool
+
+	// noConfig is set for the commands which don't read the image config, and so don't accept the config flags
+	noConfig bool
+}
+
+var commands = []command{
+	{name: "check", summary: "Output the targets missing their tag as a GitHub Actions matrix (default)", run: checker.Run, checksRegistries: true},
+	{name: "verify", summary: "Fail if a changed image's tag already exists in every target", run: checker.Verify, checksRegistries: true},
+	{name: "validate", summary: "Validate the image config without checking the registries", run: checker.Validate},
+	{name: "list", summary: "List every image target without checking the registries", run: checker.List},
+	{name: "explain", summary: "Explain whether each target will be built, and why", run: checker.Explain, checksRegistries: true},
+	{name: "render", summary: "Output the resolved image config with the defaults applied", run: checker.Render},
+	{name: "schema", summary: "Output the JSON Schema of the config files", run: checker.Schema, noConfig: true},
+}
+
 func main() {
-	l := os.Getenv("LOG_LEVEL")
-	if err := setLogLevel(l); err != nil {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+func run(args []string, stderr io.Writer) int {
+	// A leading --help is only the global help when no command is given, so that <command> --help shows its flags
+	if len(args) > 0 && isHelpFlag(args[0]) {
+		printUsage(stderr)
+		return 0
+	}
+
+	name := defaultCommand
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		printUsage(stderr)
+		return 0
+	}
+
+	cmd, ok := findCommand(name)
+	if !ok {
+		_, _ = fmt.Fprintf(stderr, "unknown command %q\n\n", name)
+		printUsage(stderr)
+		return 2
+	}
+
+	opts, logLevel, err := parseFlags(cmd, args, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		_, _ = fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	if err = setLogLevel(logLevel); err != nil {
 		slog.Error("setting log level", "err", err)
-		os.Exit(1)
+		return 1
 	}
 
-	// Default to current directory
-	imageDirectory := os.Getenv("IMAGE_DIRECTORY")
-	if imageDirectory == "" {
-		imageDirectory = "."
+	if err = cmd.run(opts); err != nil {
+		slog.Error("whilst running", "command", cmd.name, "err", err)
+		return 1
 	}
 
-	if err := checker.Run(imageDirectory); err != nil {
-		slog.Error("whilst running", "err", err)
-		os.Exit(1)
+	return 0
+}
+
+// parseFlags parses the flags of the command. Flags not set fall back to their environment variable
+func parseFlags(cmd command, args []string, stderr io.Writer) (checker.Options, string, error) {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(stderr, "Usage: %s %s [flags]\n\n%s\n\nFlags:\n", appName, cmd.name, cmd.summary)
+		fs.PrintDefaults()
 	}
+
+	opts := checker.Options{}
+	fs.StringVar(&opts.Format, "format", os.Getenv("ECR_IMAGE_CHECKER_FORMAT"), "output `format`, defaults to the command's primary format (env ECR_IMAGE_CHECKER_FORMAT)")
+	logLevel := fs.String("log-level", os.Getenv("LOG_LEVEL"), "log `level`: debug, info, warn or error (env LOG_LEVEL)")
+
+	var err error
+	if !cmd.noConfig {
+		fs.StringVar(&opts.ImageDirectory, "dir", envOrDefault("IMAGE_DIRECTORY", "."), "base `directory` to scan for image config (env IMAGE_DIRECTORY)")
+		fs.StringVar(&opts.DefaultsFile, "defaults", os.Getenv("ECR_IMAGE_CHECKER_DEFAULTS_FILE"), "`path` of the root defaults file, defaults to the nearest config-defaults.yml in --dir or its parents (env ECR_IMAGE_CHECKER_DEFAULTS_FILE)")
+		fs.StringVar(&opts.SinceRef, "since", os.Getenv("ECR_IMAGE_CHECKER_SINCE_REF"), "only include images changed since this git `ref` (env ECR_IMAGE_CHECKER_SINCE_REF)")
+		fs.StringVar(&opts.UntilRef, "until", envOrDefault("ECR_IMAGE_CHECKER_UNTIL_REF", "HEAD"), "git `ref` changes are compared up to (env ECR_IMAGE_CHECKER_UNTIL_REF)")
+		fs.StringVar(&opts.Environment, "environment", os.Getenv("ECR_IMAGE_CHECKER_ENVIRONMENT"), "only include the targets of the named `environment` in config-defaults.yml (env ECR_IMAGE_CHECKER_ENVIRONMENT)")
+
+		var allowUnknownFields bool
+		if allowUnknownFields, err = envBool("ECR_IMAGE_CHECKER_ALLOW_UNKNOWN_FIELDS"); err != nil {
+			return checker.Options{}, "", err
+		}
+		fs.BoolVar(&opts.AllowUnknownFields, "allow-unknown-fields", allowUnknownFields, "ignore unknown keys in the config files rather than failing (env ECR_IMAGE_CHECKER_ALLOW_UNKNOWN_FIELDS)")
+
+		fs.Func("include", "only include images whose directory, relative to --dir, matches the `glob`. Repeatable (env ECR_IMAGE_CHECKER_INCLUDE, comma separated)", appendTo(&opts.Include))
+		fs.Func("exclude", "skip images whose directory, relative to --dir, matches the `glob`. Repeatable (env ECR_IMAGE_CHECKER_EXCLUDE, comma separated)", appendTo(&opts.Exclude))
+	}
+
+	opts.Concurrency = checker.DefaultConcurrency
+	if cmd.checksRegistries {
+		concurrency := checker.DefaultConcurrency
+		if v := os.Getenv("ECR_IMAGE_CHECKER_CONCURRENCY"); v != "" {
+			if concurrency, err = strconv.Atoi(v); err != nil {
+				return checker.Options{}, "", fmt.Errorf("parsing ECR_IMAGE_CHECKER_CONCURRENCY: %w", err)
+			}
+		}
+		fs.IntVar(&opts.Concurrency, "concurrency", concurrency, "maximum `number` of targets checked in parallel (env ECR_IMAGE_CHECKER_CONCURRENCY)")
+	}
+
+	if err = fs.Parse(args); err != nil {
+		return checker.Options{}, "", err
+	}
+
+	if fs.NArg() > 0 {
+		return checker.Options{}, "", fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	// The flags replace, rather than add to, the globs set in the environment
+	if !cmd.noConfig {
+		if opts.Include == nil {
+			opts.Include = envList("ECR_IMAGE_CHECKER_INCLUDE")
+		}
+		if opts.Exclude == nil {
+			opts.Exclude = envList("ECR_IMAGE_CHECKER_EXCLUDE")
+		}
+	}
+
+	return opts, *logLevel, nil
+}
+
+func printUsage(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "Usage: %s [command] [flags]\n\nCommands:\n", appName)
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(w, "  %-10s%s\n", cmd.name, cmd.summary)
+	}
+	_, _ = fmt.Fprintf(w, "\nRun '%s <command> --help' for the flags of a command\n", appName)
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+
+	return command{}, false
+}
+
+func isHelpFlag(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+func envBool(key string) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("parsing %s: %w", key, err)
+	}
+
+	return b, nil
+}
+
+func envList(key string) []string {
+	var values []string
+	for v := range strings.SplitSeq(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// appendTo returns a flag.Func handler which collects every use of a repeatable flag
+func appendTo(values *[]string) func(string) error {
+	return func(v string) error {
+		*values = append(*values, v)
+		return nil
+	}
+}
+
+func envOrDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return fallback
 }
 
 func setLogLevel(level string) error {
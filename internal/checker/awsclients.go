@@ -0,0 +1,106 @@
+package checker
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecrpublic"
+)
+
+// awsClientCache shares AWS clients and assumed role credentials across all repos and targets,
+// so that each IAM role is only assumed once per run regardless of how many images use it
+type awsClientCache struct {
+	baseCfg   aws.Config
+	stsClient stscreds.AssumeRoleAPIClient
+
+	mu               sync.Mutex
+	credentials      map[string]*aws.CredentialsCache // keyed by role ARN
+	ecrClients       map[string]*ecr.Client           // keyed by account/region/role ARN
+	ecrPublicClients map[string]*ecrpublic.Client     // keyed by account/region/role ARN
+}
+
+func newAWSClientCache(baseCfg aws.Config, stsClient stscreds.AssumeRoleAPIClient) *awsClientCache {
+	return &awsClientCache{
+		baseCfg:          baseCfg,
+		stsClient:        stsClient,
+		credentials:      make(map[string]*aws.CredentialsCache),
+		ecrClients:       make(map[string]*ecr.Client),
+		ecrPublicClients: make(map[string]*ecrpublic.Client),
+	}
+}
+
+// ecrClient returns the cached ECR client for the target.
+// The registry ID to query is returned when not assuming a role, as the remote registry must then be set explicitly
+func (a *awsClientCache) ecrClient(target Target, repoName string) (*ecr.Client, *string) {
+	key := clientCacheKey(target, *target.AwsRegion)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	client, ok := a.ecrClients[key]
+	if !ok {
+		client = ecr.NewFromConfig(a.configFor(target, *target.AwsRegion, repoName))
+		a.ecrClients[key] = client
+	}
+
+	return client, registryIDFor(target)
+}
+
+// ecrPublicClient returns the cached ECR Public client for the target
+func (a *awsClientCache) ecrPublicClient(target Target, repoName string) (*ecrpublic.Client, *string) {
+	key := clientCacheKey(target, ecrPublicRegion)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	client, ok := a.ecrPublicClients[key]
+	if !ok {
+		client = ecrpublic.NewFromConfig(a.configFor(target, ecrPublicRegion, repoName))
+		a.ecrPublicClients[key] = client
+	}
+
+	return client, registryIDFor(target)
+}
+
+// configFor returns a copy of the base AWS config for the region, using the shared assumed role credentials if
+// the target has an IAM role set. Must be called with the lock held
+func (a *awsClientCache) configFor(target Target, region, repoName string) aws.Config {
+	cfg := a.baseCfg.Copy()
+	cfg.Region = region
+
+	// The value might be empty if we want to override a role name being set at the default level
+	if strPtrEmpty(target.AwsRoleName) {
+		slog.Debug("No assume IAM role defined. Using normal credential chain", "repo", repoName)
+		return cfg
+	}
+
+	creds, ok := a.credentials[target.AWSRoleARN]
+	if !ok {
+		slog.Debug("Assuming role", "role", target.AWSRoleARN, "repo", repoName)
+		creds = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(a.stsClient, target.AWSRoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = appName
+		}))
+		a.credentials[target.AWSRoleARN] = creds
+	} else {
+		slog.Debug("Reusing assumed role credentials", "role", target.AWSRoleARN, "repo", repoName)
+	}
+	cfg.Credentials = creds
+
+	return cfg
+}
+
+func clientCacheKey(target Target, region string) string {
+	return fmt.Sprintf("%s/%s/%s", readStrPointer(target.AwsAccountId), region, target.AWSRoleARN)
+}
+
+// registryIDFor returns the account ID to query when not assuming a role
+func registryIDFor(target Target) *string {
+	if strPtrEmpty(target.AwsRoleName) {
+		return target.AwsAccountId
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	stsTypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSTS counts the AssumeRole calls per role ARN
+type fakeSTS struct {
+	mu    sync.Mutex
+	calls map[string]int
+	total atomic.Int32
+}
+
+func (f *fakeSTS) AssumeRole(_ context.Context, input *sts.AssumeRoleInput, _ ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	f.mu.Lock()
+	f.calls[*input.RoleArn]++
+	f.mu.Unlock()
+	f.total.Add(1)
+
+	return &sts.AssumeRoleOutput{
+		Credentials: &stsTypes.Credentials{
+			AccessKeyId:     aws.String("AKIA" + *input.RoleArn),
+			SecretAccessKey: aws.String("secret"),
+			SessionToken:    aws.String("token"),
+			Expiration:      aws.Time(time.Now().Add(time.Hour)),
+		},
+	}, nil
+}
+
+func Test_awsClientCache(t *testing.T) {
+	stsClient := &fakeSTS{calls: make(map[string]int)}
+	cache := newAWSClientCache(aws.Config{Region: "eu-west-2"}, stsClient)
+
+	roleA := "arn:aws:iam::111111111111:role/role-a"
+	roleB := "arn:aws:iam::222222222222:role/role-b"
+
+	targets := []Target{
+		{AwsAccountId: aws.String("111111111111"), AwsRegion: aws.String("eu-west-1"), AwsRoleName: aws.String("role-a"), AWSRoleARN: roleA},
+		{AwsAccountId: aws.String("111111111111"), AwsRegion: aws.String("eu-west-2"), AwsRoleName: aws.String("role-a"), AWSRoleARN: roleA},
+		{AwsAccountId: aws.String("222222222222"), AwsRegion: aws.String("eu-west-1"), AwsRoleName: aws.String("role-b"), AWSRoleARN: roleB},
+	}
+
+	// Simulate many repos sharing the same targets, checked concurrently
+	errs := make([]error, 30)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			client, _ := cache.ecrClient(targets[i%len(targets)], fmt.Sprintf("repo-%d", i))
+			_, errs[i] = client.Options().Credentials.Retrieve(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	for _, target := range targets {
+		client, registryID := cache.ecrClient(target, "repo-1")
+		require.Nil(t, registryID, "registry ID is not set when assuming a role")
+		require.Equal(t, *target.AwsRegion, client.Options().Region)
+	}
+
+	require.Equal(t, 1, stsClient.calls[roleA], "role should be assumed once across regions")
+	require.Equal(t, 1, stsClient.calls[roleB])
+	require.Equal(t, int32(2), stsClient.total.Load())
+	require.Len(t, cache.ecrClients, 3, "one client per account/region/role")
+
+	// ECR Public shares the assumed role credentials
+	publicClient, _ := cache.ecrPublicClient(targets[0], "repo-1")
+	require.Equal(t, ecrPublicRegion, publicClient.Options().Region)
+	_, err := publicClient.Options().Credentials.Retrieve(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int32(2), stsClient.total.Load())
+}
+
+func Test_awsClientCache_noRole(t *testing.T) {
+	stsClient := &fakeSTS{calls: make(map[string]int)}
+	cache := newAWSClientCache(aws.Config{Region: "eu-west-2"}, stsClient)
+
+	target := Target{AwsAccountId: aws.String("111111111111"), AwsRegion: aws.String("eu-west-1")}
+
+	first, registryID := cache.ecrClient(target, "repo-1")
+	require.Equal(t, "111111111111", *registryID, "registry ID is required when not assuming a role")
+
+	second, _ := cache.ecrClient(target, "repo-2")
+	require.Same(t, first, second)
+	require.Equal(t, int32(0), stsClient.total.Load())
+}
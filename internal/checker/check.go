@@ -0,0 +1,205 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	buildReasonTagMissing       = "tag missing"
+	buildReasonSomeTagsMissing  = "tags missing"
+	buildReasonPlatformsMissing = "platforms missing from existing tag"
+)
+
+// checkJob is a single target to be checked against its registry
+type checkJob struct {
+	key    string
+	index  int
+	repo   repoConfig
+	target *Target
+}
+
+// checkRegistries connects to AWS and checks every target against its registry
+func (c *config) checkRegistries(ctx context.Context, concurrency int) error {
+	if err := c.connectAWS(ctx); err != nil {
+		return fmt.Errorf("connecting to AWS: %w", err)
+	}
+
+	if err := c.checkTargets(ctx, concurrency); err != nil {
+		return fmt.Errorf("checking remote Docker tags: %w", err)
+	}
+
+	return nil
+}
+
+// checkTargets checks every target of every repo against its registry using a bounded pool of workers.
+// Errors are collected per target so a single failure doesn't hide the others
+func (c *config) checkTargets(ctx context.Context, concurrency int) error {
+	jobs := make([]checkJob, 0)
+	for _, key := range sortedKeys(c.repos) {
+		repo := c.repos[key]
+		for idx, target := range repo.Targets {
+			jobs = append(jobs, checkJob{key: key, index: idx, repo: repo, target: target})
+		}
+	}
+
+	slog.Debug("Checking targets", "count", len(jobs), "concurrency", concurrency)
+
+	// Each job only writes to its own index and target so no further locking is required
+	errs := make([]error, len(jobs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, job := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := c.checkTarget(ctx, job); err != nil {
+				errs[i] = fmt.Errorf("%s target index %d (%s): %w", job.key, job.index, job.target.FullImageRef, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (c *config) checkTarget(ctx context.Context, job checkJob) error {
+	registry, err := c.newRegistry(*job.target, *job.repo.RepoName)
+	if err != nil {
+		return fmt.Errorf("setting up registry client: %w", err)
+	}
+
+	return checkImageTags(ctx, registry, job.repo, job.target)
+}
+
+func checkImageTags(ctx context.Context, registry Registry, repo repoConfig, target *Target) error {
+	tags := repo.tags()
+	found, err := tagsExist(ctx, registry, *repo.RepoName, tags)
+	if err != nil {
+		return fmt.Errorf("checking tags %s for %s: %w", strings.Join(tags, ","), *repo.RepoName, err)
+	}
+
+	for i := range target.Tags {
+		target.Tags[i].Missing = !found[target.Tags[i].Tag]
+	}
+
+	missingTags := make([]string, 0)
+	for _, tag := range tags {
+		if !found[tag] {
+			missingTags = append(missingTags, tag)
+		}
+	}
+
+	// Flag the Docker tags as needing to be built. The build pushes every tag, so a partially pushed set is rebuilt
+	if len(missingTags) > 0 {
+		target.RemoteTagMissing = true
+		target.BuildReason = buildReasonTagMissing
+		if len(missingTags) < len(tags) {
+			target.BuildReason = fmt.Sprintf("%s: %s", buildReasonSomeTagsMissing, strings.Join(missingTags, ","))
+		}
+		return nil
+	}
+
+	// Existing tags may not have been built for all the platforms now in the config
+	missing := make([]string, 0)
+	for _, tag := range tags {
+		tagMissing, err := missingPlatforms(ctx, registry, *repo.RepoName, tag, target.platforms(repo))
+		if err != nil {
+			// Policies only granting ecr:ListImages can still check the tags, so the platforms are skipped rather
+			// than failing the run
+			if isAccessDenied(err) {
+				slog.Warn("Not permitted to get the image manifest. Skipping the platform check", "repo", *repo.RepoName, "tag", tag, "err", err)
+				return nil
+			}
+			return fmt.Errorf("checking platforms of %s:%s: %w", *repo.RepoName, tag, err)
+		}
+
+		for _, platform := range tagMissing {
+			if !slices.Contains(missing, platform) {
+				missing = append(missing, platform)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		slog.Debug("Existing tags are missing platforms", "repo", *repo.RepoName, "tags", tags, "platforms", missing)
+		target.RemoteTagMissing = true
+		target.BuildReason = fmt.Sprintf("%s: %s", buildReasonPlatformsMissing, strings.Join(missing, ","))
+	}
+
+	return nil
+}
+
+// missingPlatforms returns the target platforms not present in the manifest list/index of the existing tag
+func missingPlatforms(ctx context.Context, registry Registry, repoName, tag string, targetPlatforms []string) ([]string, error) {
+	if len(targetPlatforms) == 0 {
+		return nil, nil
+	}
+
+	manifest, err := registry.DescribeManifest(ctx, repoName, tag)
+	if err != nil {
+		// The tag has been removed since it was checked
+		if errors.Is(err, ErrManifestNotFound) {
+			return targetPlatforms, nil
+		}
+		return nil, err
+	}
+
+	// A single image manifest doesn't list its platform, but can only ever contain one which is read from its config
+	if len(manifest.Platforms) == 0 {
+		if len(targetPlatforms) > 1 {
+			return targetPlatforms, nil
+		}
+
+		platform, err := configPlatform(ctx, registry, repoName, manifest)
+		if err != nil {
+			return nil, err
+		}
+		if platform != "" && !platformMatches(targetPlatforms[0], platform) {
+			return targetPlatforms, nil
+		}
+		return nil, nil
+	}
+
+	missing := make([]string, 0)
+	for _, platform := range targetPlatforms {
+		if !slices.ContainsFunc(manifest.Platforms, func(p string) bool { return platformMatches(platform, p) }) {
+			missing = append(missing, platform)
+		}
+	}
+
+	return missing, nil
+}
+
+// configPlatform returns the platform of a single image manifest from its config blob. An empty platform is returned
+// if the registry can't read config blobs, in which case the tag is assumed to match the configured platform
+func configPlatform(ctx context.Context, registry Registry, repoName string, manifest Manifest) (string, error) {
+	reader, ok := registry.(configPlatformReader)
+	if !ok || manifest.ConfigDigest == "" {
+		slog.Debug("Unable to read the platform of single manifest", "repo", repoName, "digest", manifest.Digest)
+		return "", nil
+	}
+
+	platform, err := reader.ConfigPlatform(ctx, repoName, manifest.ConfigDigest)
+	if err != nil {
+		return "", fmt.Errorf("reading image config %s: %w", manifest.ConfigDigest, err)
+	}
+
+	return platform, nil
+}
+
+// platformMatches reports whether the configured platform matches the one in the manifest.
+// A configured platform without a variant matches any variant e.g. linux/arm64 matches linux/arm64/v8
+func platformMatches(configured, manifest string) bool {
+	return configured == manifest || strings.HasPrefix(manifest, configured+"/")
+}
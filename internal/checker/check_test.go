@@ -0,0 +1,371 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checkImageTags(t *testing.T) {
+	registry := newFakeRegistry()
+	registry.addImage("repo-1", "v1", Manifest{})
+	registry.addImage("repo-1", "v2", Manifest{})
+
+	cases := []struct {
+		testName       string
+		conf           repoConfig
+		expectTagFound bool
+		expectError    bool
+	}{
+		{
+			testName: "Docker tag found",
+			conf: repoConfig{
+				RepoName: aws.String("repo-1"),
+				RepoTag:  aws.String("v2"),
+				Targets: []*Target{
+					{
+						AwsAccountId: aws.String("1111111111"),
+						AwsRegion:    aws.String("eu-west-3"),
+					},
+				},
+			},
+			expectTagFound: true,
+		},
+		{
+			testName: "Docker tag missing",
+			conf: repoConfig{
+				RepoName: aws.String("repo-1"),
+				RepoTag:  aws.String("missing"),
+				Targets: []*Target{
+					{
+						AwsAccountId: aws.String("1111111111"),
+						AwsRegion:    aws.String("eu-west-3"),
+					},
+				},
+			},
+			expectTagFound: false,
+		},
+		{
+			testName: "Registry error",
+			conf: repoConfig{
+				RepoName: aws.String(fakeRegistryErrorRepo),
+				RepoTag:  aws.String("v1"),
+				Targets: []*Target{
+					{
+						AwsAccountId: aws.String("1111111111"),
+						AwsRegion:    aws.String("eu-west-3"),
+					},
+				},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkImageTags(context.Background(), registry, tc.conf, tc.conf.Targets[0])
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			if tc.expectTagFound {
+				require.Equal(t, false, tc.conf.Targets[0].RemoteTagMissing)
+			} else {
+				require.Equal(t, true, tc.conf.Targets[0].RemoteTagMissing)
+			}
+		})
+	}
+}
+
+// countingRegistry wraps a Registry, tracking the peak number of concurrent TagExists calls
+type countingRegistry struct {
+	Registry
+	inFlight *atomic.Int32
+	peak     *atomic.Int32
+}
+
+func (r countingRegistry) TagExists(ctx context.Context, repoName, tag string) (bool, error) {
+	n := r.inFlight.Add(1)
+	defer r.inFlight.Add(-1)
+
+	for {
+		p := r.peak.Load()
+		if n <= p || r.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	return r.Registry.TagExists(ctx, repoName, tag)
+}
+
+func Test_checkTargets(t *testing.T) {
+	registry := newFakeRegistry()
+	registry.addImage("repo-1", "v1", Manifest{})
+
+	repos := make(map[string]repoConfig)
+	for i := range 20 {
+		repos[fmt.Sprintf("image-%02d/config.yml", i)] = repoConfig{
+			RepoName: aws.String("repo-1"),
+			RepoTag:  aws.String(fmt.Sprintf("v%d", i%2)),
+			Targets:  []*Target{{FullImageRef: "ref-1"}, {FullImageRef: "ref-2"}},
+		}
+	}
+
+	var inFlight, peak atomic.Int32
+	c := config{
+		repos: repos,
+		newRegistry: func(_ Target, _ string) (Registry, error) {
+			return countingRegistry{Registry: registry, inFlight: &inFlight, peak: &peak}, nil
+		},
+	}
+
+	err := c.checkTargets(context.Background(), 3)
+	require.NoError(t, err)
+	require.LessOrEqual(t, peak.Load(), int32(3), "concurrency limit should be respected")
+
+	missing := filterMissingTags(c.repos)
+	require.Len(t, missing, 20)
+	require.Equal(t, missing, filterMissingTags(c.repos), "output ordering should be deterministic")
+}
+
+func Test_checkTargets_aggregatesErrors(t *testing.T) {
+	registry := newFakeRegistry()
+	registry.addImage("repo-1", "v1", Manifest{})
+
+	c := config{
+		repos: map[string]repoConfig{
+			"image-1/config.yml": {
+				RepoName: aws.String(fakeRegistryErrorRepo),
+				RepoTag:  aws.String("v1"),
+				Targets:  []*Target{{FullImageRef: "ref-1"}, {FullImageRef: "ref-2"}},
+			},
+			"image-2/config.yml": {
+				RepoName: aws.String("repo-1"),
+				RepoTag:  aws.String("missing"),
+				Targets:  []*Target{{FullImageRef: "ref-3"}},
+			},
+			"image-3/config.yml": {
+				RepoName: aws.String("setup-error"),
+				RepoTag:  aws.String("v1"),
+				Targets:  []*Target{{FullImageRef: "ref-4"}},
+			},
+		},
+		newRegistry: func(_ Target, repoName string) (Registry, error) {
+			if repoName == "setup-error" {
+				return nil, errors.New("no credentials")
+			}
+			return registry, nil
+		},
+	}
+
+	err := c.checkTargets(context.Background(), 2)
+	require.Error(t, err)
+	require.ErrorContains(t, err, "image-1/config.yml target index 0 (ref-1)")
+	require.ErrorContains(t, err, "image-1/config.yml target index 1 (ref-2)")
+	require.ErrorContains(t, err, "image-3/config.yml target index 0 (ref-4)")
+
+	require.True(t, c.repos["image-2/config.yml"].Targets[0].RemoteTagMissing, "other targets should still be checked")
+}
+
+func Test_checkImageTags_platforms(t *testing.T) {
+	registry := newFakeRegistry()
+	registry.addImage("repo-1", "multi-arch", Manifest{MediaType: mediaTypeOCIIndex, Platforms: []string{"linux/amd64", "linux/arm64/v8"}})
+	registry.addImage("repo-1", "amd64-only", Manifest{MediaType: mediaTypeOCIIndex, Platforms: []string{"linux/amd64"}})
+	registry.addImage("repo-1", "single-manifest", Manifest{MediaType: mediaTypeOCIManifest, ConfigDigest: "sha256:amd64"})
+	registry.addImage("repo-1", "single-manifest-no-config", Manifest{MediaType: mediaTypeOCIManifest})
+	registry.addConfig("sha256:amd64", "linux/amd64")
+
+	cases := []struct {
+		testName        string
+		tag             string
+		targetPlatforms []string
+		expectMissing   bool
+		expectReason    string
+	}{
+		{
+			testName:        "All platforms present",
+			tag:             "multi-arch",
+			targetPlatforms: []string{"linux/amd64", "linux/arm64"},
+			expectMissing:   false,
+		},
+		{
+			testName:        "Platform added to config",
+			tag:             "amd64-only",
+			targetPlatforms: []string{"linux/amd64", "linux/arm64"},
+			expectMissing:   true,
+			expectReason:    "platforms missing from existing tag: linux/arm64",
+		},
+		{
+			testName:        "Single manifest with one platform configured",
+			tag:             "single-manifest",
+			targetPlatforms: []string{"linux/amd64"},
+			expectMissing:   false,
+		},
+		{
+			testName:        "Single manifest with a different platform configured",
+			tag:             "single-manifest",
+			targetPlatforms: []string{"linux/arm64"},
+			expectMissing:   true,
+			expectReason:    "platforms missing from existing tag: linux/arm64",
+		},
+		{
+			testName:        "Single manifest without a config digest",
+			tag:             "single-manifest-no-config",
+			targetPlatforms: []string{"linux/arm64"},
+			expectMissing:   false,
+		},
+		{
+			testName:        "Single manifest with multiple platforms configured",
+			tag:             "single-manifest",
+			targetPlatforms: []string{"linux/amd64", "linux/arm64"},
+			expectMissing:   true,
+			expectReason:    "platforms missing from existing tag: linux/amd64,linux/arm64",
+		},
+		{
+			testName:        "Tag missing",
+			tag:             "missing",
+			targetPlatforms: []string{"linux/amd64"},
+			expectMissing:   true,
+			expectReason:    buildReasonTagMissing,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			repo := repoConfig{
+				RepoName:        aws.String("repo-1"),
+				RepoTag:         aws.String(tc.tag),
+				TargetPlatforms: tc.targetPlatforms,
+				Targets:         []*Target{{}},
+			}
+
+			err := checkImageTags(context.Background(), registry, repo, repo.Targets[0])
+			require.NoError(t, err)
+			require.Equal(t, tc.expectMissing, repo.Targets[0].RemoteTagMissing)
+			require.Equal(t, tc.expectReason, repo.Targets[0].BuildReason)
+		})
+	}
+}
+
+func Test_checkImageTags_manifestAccessDenied(t *testing.T) {
+	registry := newFakeRegistry()
+	registry.addImage(fakeRegistryDeniedRepo, "v1", Manifest{})
+
+	repo := repoConfig{
+		RepoName:        aws.String(fakeRegistryDeniedRepo),
+		RepoTag:         aws.String("v1"),
+		TargetPlatforms: []string{"linux/amd64", "linux/arm64"},
+		Targets:         []*Target{{}},
+	}
+
+	require.NoError(t, checkImageTags(context.Background(), registry, repo, repo.Targets[0]), "the platform check should be skipped")
+	require.False(t, repo.Targets[0].RemoteTagMissing)
+}
+
+func Test_checkImageTags_multipleTags(t *testing.T) {
+	registry := newFakeRegistry()
+	registry.addImage("repo-1", "alpine-3", Manifest{MediaType: mediaTypeOCIIndex, Platforms: []string{"linux/amd64"}})
+	registry.addImage("repo-1", "alpine-3.20", Manifest{MediaType: mediaTypeOCIIndex, Platforms: []string{"linux/amd64", "linux/arm64"}})
+
+	cases := []struct {
+		testName      string
+		tags          []string
+		platforms     []string
+		expectMissing []bool
+		expectReason  string
+	}{
+		{
+			testName:      "All tags exist",
+			tags:          []string{"alpine-3", "alpine-3.20"},
+			platforms:     []string{"linux/amd64"},
+			expectMissing: []bool{false, false},
+		},
+		{
+			testName:      "Some tags missing",
+			tags:          []string{"alpine-3", "alpine-3.20", "alpine-3.20.1"},
+			platforms:     []string{"linux/amd64"},
+			expectMissing: []bool{false, false, true},
+			expectReason:  "tags missing: alpine-3.20.1",
+		},
+		{
+			testName:      "All tags missing",
+			tags:          []string{"alpine-4", "alpine-4.0"},
+			platforms:     []string{"linux/amd64"},
+			expectMissing: []bool{true, true},
+			expectReason:  buildReasonTagMissing,
+		},
+		{
+			testName:      "Platform missing from one tag",
+			tags:          []string{"alpine-3", "alpine-3.20"},
+			platforms:     []string{"linux/amd64", "linux/arm64"},
+			expectMissing: []bool{false, false},
+			expectReason:  "platforms missing from existing tag: linux/arm64",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			target := &Target{}
+			for _, tag := range tc.tags {
+				target.Tags = append(target.Tags, TagStatus{Tag: tag})
+			}
+			repo := repoConfig{
+				RepoName:        aws.String("repo-1"),
+				RepoTags:        tc.tags,
+				TargetPlatforms: tc.platforms,
+				Targets:         []*Target{target},
+			}
+
+			require.NoError(t, checkImageTags(context.Background(), registry, repo, target))
+
+			missing := make([]bool, 0, len(target.Tags))
+			for _, tag := range target.Tags {
+				missing = append(missing, tag.Missing)
+			}
+			require.Equal(t, tc.expectMissing, missing)
+			require.Equal(t, tc.expectReason != "", target.RemoteTagMissing)
+			require.Equal(t, tc.expectReason, target.BuildReason)
+		})
+	}
+}
+
+func Test_checkImageTags_targetPlatforms(t *testing.T) {
+	registry := newFakeRegistry()
+	registry.addImage("repo-1", "amd64-only", Manifest{MediaType: mediaTypeOCIIndex, Platforms: []string{"linux/amd64"}})
+
+	repo := repoConfig{
+		RepoName:        aws.String("repo-1"),
+		RepoTag:         aws.String("amd64-only"),
+		TargetPlatforms: []string{"linux/amd64", "linux/arm64"},
+		Targets:         []*Target{{}, {TargetPlatforms: []string{"linux/amd64"}}},
+	}
+
+	for _, target := range repo.Targets {
+		require.NoError(t, checkImageTags(context.Background(), registry, repo, target))
+	}
+
+	require.True(t, repo.Targets[0].RemoteTagMissing)
+	require.False(t, repo.Targets[1].RemoteTagMissing, "the target's own platforms should be checked")
+}
+
+func Test_platformMatches(t *testing.T) {
+	require.True(t, platformMatches("linux/amd64", "linux/amd64"))
+	require.True(t, platformMatches("linux/arm64", "linux/arm64/v8"))
+	require.False(t, platformMatches("linux/arm/v7", "linux/arm/v6"))
+	require.False(t, platformMatches("linux/arm", "linux/arm64"))
+}
@@ -0,0 +1,194 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats. Not every command supports every format
+const (
+	FormatGitHub = "github"
+	FormatJSON   = "json"
+	FormatText   = "text"
+	FormatYAML   = "yaml"
+)
+
+// explainReasonUpToDate is reported for targets which won't be built
+const explainReasonUpToDate = "tag exists with all target platforms"
+
+// Validate parses and validates the image config without checking the registries
+func Validate(opts Options) error {
+	format, err := opts.format(FormatText, FormatJSON)
+	if err != nil {
+		return err
+	}
+
+	c, err := loadConfig(opts)
+
+	var problems ValidationErrors
+	if err != nil && !errors.As(err, &problems) {
+		return err
+	}
+
+	if format == FormatJSON {
+		if problems == nil {
+			problems = ValidationErrors{}
+		}
+		if err = writeJSON(opts.output(), problems); err != nil {
+			return err
+		}
+	}
+
+	if len(problems) > 0 {
+		// Report each problem on its own line so they can all be fixed in one pass
+		if format == FormatText {
+			_, _ = fmt.Fprintln(opts.output(), problems)
+		}
+
+		return fmt.Errorf("found %d problem(s) in the image config", len(problems))
+	}
+
+	if format == FormatText {
+		_, err = fmt.Fprintf(opts.output(), "%d image config file(s) are valid\n", len(c.repos))
+	}
+
+	return err
+}
+
+// List outputs every target of every image, without checking the registries
+func List(opts Options) error {
+	format, err := opts.format(FormatText, FormatJSON)
+	if err != nil {
+		return err
+	}
+
+	c, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	targets := allTargets(c.repos)
+	if format == FormatJSON {
+		return writeJSON(opts.output(), targets)
+	}
+
+	tw := tabwriter.NewWriter(opts.output(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "DIRECTORY\tREGISTRY\tIMAGE\tPLATFORMS")
+	for _, target := range targets {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", target.WorkingDirectory, readStrPointer(target.RegistryType), target.FullImageRef, target.TargetPlatformStr)
+	}
+
+	return tw.Flush()
+}
+
+// Explain checks every target against the registries and outputs whether it will be built, and why
+func Explain(opts Options) error {
+	format, err := opts.format(FormatText, FormatJSON)
+	if err != nil {
+		return err
+	}
+
+	c, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	if err = c.checkRegistries(context.Background(), opts.Concurrency); err != nil {
+		return err
+	}
+
+	targets := allTargets(c.repos)
+	if format == FormatJSON {
+		return writeJSON(opts.output(), targets)
+	}
+
+	tw := tabwriter.NewWriter(opts.output(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "IMAGE\tBUILD\tREASON")
+	for _, target := range targets {
+		build, reason := "no", explainReasonUpToDate
+		if target.RemoteTagMissing {
+			build, reason = "yes", target.BuildReason
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", target.FullImageRef, build, reason)
+	}
+
+	return tw.Flush()
+}
+
+// Render outputs the resolved config of every image, keyed by config file, with the defaults applied
+func Render(opts Options) error {
+	format, err := opts.format(FormatYAML, FormatJSON)
+	if err != nil {
+		return err
+	}
+
+	c, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	if format == FormatJSON {
+		return writeJSON(opts.output(), c.repos)
+	}
+
+	enc := yaml.NewEncoder(opts.output())
+	enc.SetIndent(2)
+	if err = enc.Encode(c.repos); err != nil {
+		return fmt.Errorf("marshalling YAML: %w", err)
+	}
+
+	return enc.Close()
+}
+
+// allTargets returns every target, in a stable order
+func allTargets(repos map[string]repoConfig) []Target {
+	targets := make([]Target, 0)
+
+	for _, key := range sortedKeys(repos) {
+		for _, target := range repos[key].Targets {
+			targets = append(targets, *target)
+		}
+	}
+
+	return targets
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("marshalling JSON: %w", err)
+	}
+
+	return nil
+}
+
+// format returns the requested output format, or the first of the supported formats if not set
+func (o Options) format(supported ...string) (string, error) {
+	if o.Format == "" {
+		return supported[0], nil
+	}
+
+	if !slices.Contains(supported, o.Format) {
+		return "", fmt.Errorf("unsupported format %q, must be one of: %s", o.Format, strings.Join(supported, ", "))
+	}
+
+	return o.Format, nil
+}
+
+func (o Options) output() io.Writer {
+	if o.Output == nil {
+		return os.Stdout
+	}
+
+	return o.Output
+}
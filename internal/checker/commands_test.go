@@ -0,0 +1,149 @@
+package checker
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// writeTestImages creates a defaults file and two images under a temp dir, returning options pointing at them
+func writeTestImages(t *testing.T) Options {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config-defaults.yml":       "default_aws_account_id: \"111111111111\"\ndefault_aws_region: eu-west-1\n",
+		"images/image-1/Dockerfile": "FROM alpine:3",
+		"images/image-2/Dockerfile": "FROM alpine:3",
+		"images/image-1/config.yml": `repo_name: image-1
+repo_tag: "1"
+target_platforms: [linux/amd64]
+`,
+		"images/image-2/config.yml": `repo_name: image-2
+repo_tag: "2"
+target_platforms: [linux/amd64, linux/arm64]
+targets:
+  - aws_region: us-east-1
+  - registry_type: ghcr
+    registry_namespace: my-org
+`,
+	})
+
+	return Options{
+		ImageDirectory: filepath.Join(dir, "images"),
+		DefaultsFile:   filepath.Join(dir, "config-defaults.yml"),
+		Concurrency:    DefaultConcurrency,
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	// Validating must not need AWS, which would fail to load this profile
+	t.Setenv("AWS_PROFILE", "ecr-image-checker-missing-profile")
+
+	opts := writeTestImages(t)
+	var out bytes.Buffer
+	opts.Output = &out
+
+	require.NoError(t, Validate(opts))
+	require.Equal(t, "2 image config file(s) are valid\n", out.String())
+
+	writeFiles(t, opts.ImageDirectory, map[string]string{
+		"image-3/Dockerfile": "FROM alpine:3",
+		"image-3/config.yml": "repo_name: image-3\ntarget_platforms: [\"\"]\n",
+	})
+	out.Reset()
+	require.EqualError(t, Validate(opts), "found 2 problem(s) in the image config")
+
+	key := filepath.Join(opts.ImageDirectory, "image-3", childConfigFile)
+	require.Equal(t, key+":1:1: repo_tag not set\n"+key+":2:20: target_platforms cannot contain empty values, index 0\n", out.String())
+
+	out.Reset()
+	opts.Format = FormatJSON
+	require.Error(t, Validate(opts))
+
+	var problems ValidationErrors
+	require.NoError(t, json.Unmarshal(out.Bytes(), &problems))
+	require.Equal(t, ValidationErrors{
+		{Path: key, Line: 1, Column: 1, Message: "repo_tag not set"},
+		{Path: key, Line: 2, Column: 20, Message: "target_platforms cannot contain empty values, index 0"},
+	}, problems)
+
+	opts.DefaultsFile = filepath.Join(t.TempDir(), "missing.yml")
+	require.Error(t, Validate(opts))
+}
+
+func Test_List(t *testing.T) {
+	opts := writeTestImages(t)
+
+	var out bytes.Buffer
+	opts.Output = &out
+	require.NoError(t, List(opts))
+	require.Contains(t, out.String(), "111111111111.dkr.ecr.eu-west-1.amazonaws.com/image-1:1")
+	require.Contains(t, out.String(), "111111111111.dkr.ecr.us-east-1.amazonaws.com/image-2:2")
+	require.Contains(t, out.String(), "ghcr.io/my-org/image-2:2")
+
+	out.Reset()
+	opts.Format = FormatJSON
+	require.NoError(t, List(opts))
+
+	var targets []Target
+	require.NoError(t, json.Unmarshal(out.Bytes(), &targets))
+	require.Len(t, targets, 3)
+	require.Equal(t, "111111111111.dkr.ecr.eu-west-1.amazonaws.com/image-1:1", targets[0].FullImageRef, "targets should be sorted by config path")
+
+	opts.Format = FormatYAML
+	require.Error(t, List(opts))
+}
+
+func Test_Render(t *testing.T) {
+	opts := writeTestImages(t)
+
+	var out bytes.Buffer
+	opts.Output = &out
+	require.NoError(t, Render(opts))
+
+	var repos map[string]repoConfig
+	require.NoError(t, yaml.Unmarshal(out.Bytes(), &repos))
+	require.Len(t, repos, 2)
+
+	repo := repos[filepath.Join(opts.ImageDirectory, "image-2", childConfigFile)]
+	require.Len(t, repo.Targets, 2)
+	require.Equal(t, "111111111111", *repo.Targets[0].AwsAccountId, "defaults should be applied")
+	require.Equal(t, registryTypeGHCR, *repo.Targets[1].RegistryType)
+	require.NotContains(t, out.String(), "full_image_ref", "calculated fields are not part of the config")
+
+	out.Reset()
+	opts.Format = FormatJSON
+	require.NoError(t, Render(opts))
+	require.NoError(t, json.Unmarshal(out.Bytes(), &map[string]repoConfig{}))
+}
+
+func TestOptions_format(t *testing.T) {
+	format, err := Options{}.format(FormatText, FormatJSON)
+	require.NoError(t, err)
+	require.Equal(t, FormatText, format)
+
+	format, err = Options{Format: FormatJSON}.format(FormatText, FormatJSON)
+	require.NoError(t, err)
+	require.Equal(t, FormatJSON, format)
+
+	_, err = Options{Format: FormatGitHub}.format(FormatText, FormatJSON)
+	require.Error(t, err)
+}
+
+func Test_allTargets(t *testing.T) {
+	repos := map[string]repoConfig{
+		"b/config.yml": {Targets: []*Target{{FullImageRef: "b1"}, {FullImageRef: "b2"}}},
+		"a/config.yml": {Targets: []*Target{{FullImageRef: "a1"}}},
+		"c/config.yml": {},
+	}
+
+	targets := allTargets(repos)
+	require.Len(t, targets, 3)
+	require.Equal(t, "a1", targets[0].FullImageRef)
+	require.Equal(t, "b2", targets[2].FullImageRef)
+}
@@ -0,0 +1,166 @@
+package checker
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlErrorLine matches the line number yaml.v3 prefixes its syntax and type errors with
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// ValidationError is a problem with a config file, positioned at the offending YAML node where known.
+// Line and Column are zero if the problem isn't specific to a node, such as a missing Dockerfile
+type ValidationError struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.Path, e.Message)
+	}
+
+	if e.Column == 0 {
+		return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Message)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Message)
+}
+
+// ValidationErrors is every problem found across all the config files, so they can be fixed in a single pass
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, problem := range e {
+		msgs = append(msgs, problem.Error())
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// sorted returns the problems ordered by file and then position, keeping the order of problems at the same position
+func (e ValidationErrors) sorted() ValidationErrors {
+	sorted := slices.Clone(e)
+	slices.SortStableFunc(sorted, func(a, b *ValidationError) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+
+	return sorted
+}
+
+// orNil avoids returning a non-nil error interface holding an empty slice
+func (e ValidationErrors) orNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// errorAt creates an error for the config file, positioned at the node found by following the mapping keys and
+// sequence indexes in nodePath
+func (c *config) errorAt(key string, msg string, nodePath ...any) *ValidationError {
+	line, column := nodePosition(c.nodes[key], nodePath...)
+	return &ValidationError{Path: key, Line: line, Column: column, Message: msg}
+}
+
+// yamlErrors converts a YAML decoding error into positioned errors. Type errors can contain a problem per node
+func yamlErrors(path string, err error) ValidationErrors {
+	msgs := []string{err.Error()}
+
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		msgs = typeErr.Errors
+	}
+
+	problems := make(ValidationErrors, 0, len(msgs))
+	for _, msg := range msgs {
+		problems = append(problems, yamlError(path, msg))
+	}
+
+	return problems
+}
+
+// unknownFieldErrors returns only the unknown key problems of a YAML decoding error, for when the other problems
+// are reported by decoding elsewhere
+func unknownFieldErrors(path string, err error) ValidationErrors {
+	problems := make(ValidationErrors, 0)
+
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return problems
+	}
+
+	for _, msg := range typeErr.Errors {
+		if m := yamlErrorLine.FindStringSubmatch(msg); m != nil && unknownFieldError.MatchString(m[2]) {
+			problems = append(problems, yamlError(path, msg))
+		}
+	}
+
+	return problems
+}
+
+func yamlError(path, msg string) *ValidationError {
+	problem := &ValidationError{Path: path, Message: msg}
+
+	if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+		problem.Line, _ = strconv.Atoi(m[1])
+		problem.Message = m[2]
+	}
+	problem.Message = describeUnknownField(problem.Message)
+
+	return problem
+}
+
+// nodePosition returns the position of the node at the path of mapping keys (string) and sequence indexes (int).
+// If the path doesn't exist, such as a missing key, the position of the closest parent is returned instead
+func nodePosition(doc *yaml.Node, nodePath ...any) (int, int) {
+	if doc == nil || doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return 0, 0
+	}
+
+	node := doc.Content[0]
+	line, column := node.Line, node.Column
+
+	for _, elem := range nodePath {
+		var next *yaml.Node
+
+		switch e := elem.(type) {
+		case string:
+			if node.Kind != yaml.MappingNode {
+				return line, column
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == e {
+					// Point at the key so errors about empty values are positioned on the key's line
+					line, column = node.Content[i].Line, node.Content[i].Column
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case int:
+			if node.Kind != yaml.SequenceNode || e < 0 || e >= len(node.Content) {
+				return line, column
+			}
+			next = node.Content[e]
+			line, column = next.Line, next.Column
+		}
+
+		if next == nil {
+			return line, column
+		}
+		node = next
+	}
+
+	return line, column
+}
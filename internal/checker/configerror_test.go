@@ -0,0 +1,161 @@
+package checker
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const positionTestYAML = `repo_name: image-1
+repo_tag: ""
+target_platforms:
+  - linux/amd64
+  - ""
+targets:
+  - aws_region: eu-west-1
+  - registry_type: oci
+`
+
+func Test_nodePosition(t *testing.T) {
+	var doc yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte(positionTestYAML), &doc))
+
+	cases := []struct {
+		testName string
+		path     []any
+		line     int
+		column   int
+	}{
+		{testName: "Document", line: 1, column: 1},
+		{testName: "Key", path: []any{"repo_tag"}, line: 2, column: 1},
+		{testName: "Sequence index", path: []any{"target_platforms", 1}, line: 5, column: 5},
+		{testName: "Nested key", path: []any{"targets", 1, "registry_type"}, line: 8, column: 5},
+		{testName: "Missing nested key falls back to parent", path: []any{"targets", 1, "registry_host"}, line: 8, column: 5},
+		{testName: "Missing index falls back to parent", path: []any{"targets", 5}, line: 6, column: 1},
+		{testName: "Missing key", path: []any{"build_args"}, line: 1, column: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			line, column := nodePosition(&doc, tc.path...)
+			require.Equal(t, tc.line, line)
+			require.Equal(t, tc.column, column)
+		})
+	}
+
+	line, column := nodePosition(nil, "repo_name")
+	require.Zero(t, line)
+	require.Zero(t, column)
+}
+
+func Test_validate_reportsAllErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"image-1/Dockerfile": "FROM alpine:3",
+		"image-1/config.yml": positionTestYAML,
+		"image-2/Dockerfile": "FROM alpine:3",
+		"image-2/config.yml": "repo_tag: v1\ntarget_platforms: [linux/amd64]\n",
+	})
+
+	c := newConfig()
+	var problems ValidationErrors
+	require.ErrorAs(t, c.parseChildConfig(dir, repoConfig{}), &problems, "values are checked as each file is parsed")
+
+	var more ValidationErrors
+	require.ErrorAs(t, c.validate(), &more)
+	err := append(problems, more...).sorted()
+
+	keyOne := filepath.Join(dir, "image-1", childConfigFile)
+	keyTwo := filepath.Join(dir, "image-2", childConfigFile)
+	require.Equal(t, keyOne+":2:1: repo_tag not set\n"+
+		keyOne+":5:5: target_platforms cannot contain empty values, index 1\n"+
+		keyOne+":7:5: aws_account_id not set for target index 0 and there is no default set\n"+
+		keyOne+":8:5: registry_host not set for target index 1\n"+
+		keyTwo+":1:1: repo_name not set\n"+
+		keyTwo+":1:1: targets not set either at the child level or via defaults", err.Error())
+
+	require.True(t, errors.As(err, &problems))
+	require.Len(t, problems, 6)
+	require.Equal(t, &ValidationError{Path: keyOne, Line: 2, Column: 1, Message: "repo_tag not set"}, problems[0])
+}
+
+func Test_parseChildConfig_collectsProblems(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"image-1/Dockerfile": "FROM alpine:3",
+		"image-1/config.yml": "repo_name: image-1\ntargets: [\n",
+		"image-2/Dockerfile": "FROM alpine:3",
+		"image-2/config.yml": "repo_name: image-2\ntarget_platforms: linux/amd64\nbuild_args: [a]\n",
+		"image-3/config.yml": "repo_name: image-3\n",
+		"image-4/Dockerfile": "FROM alpine:3",
+		"image-4/config.yml": "repo_name: image-4\n",
+	})
+
+	c := newConfig()
+	err := c.parseChildConfig(dir, repoConfig{})
+
+	var problems ValidationErrors
+	require.True(t, errors.As(err, &problems))
+	require.Len(t, problems, 4)
+
+	require.Equal(t, filepath.Join(dir, "image-1", childConfigFile), problems[0].Path)
+	require.Equal(t, 2, problems[0].Line)
+
+	require.Equal(t, filepath.Join(dir, "image-2", childConfigFile), problems[1].Path)
+	require.Equal(t, 2, problems[1].Line)
+	require.Equal(t, 3, problems[2].Line)
+
+	require.Equal(t, filepath.Join(dir, "image-3", childConfigFile), problems[3].Path)
+	require.Contains(t, problems[3].Message, "unable to find Dockerfile")
+
+	require.Len(t, c.repos, 1, "valid configs should still be parsed")
+}
+
+func TestValidationError_Error(t *testing.T) {
+	cases := []struct {
+		testName string
+		err      *ValidationError
+		expected string
+	}{
+		{
+			testName: "Line and column",
+			err:      &ValidationError{Path: "images/foo/config.yml", Line: 12, Column: 3, Message: "repo_tag not set"},
+			expected: "images/foo/config.yml:12:3: repo_tag not set",
+		},
+		{
+			testName: "Line only",
+			err:      &ValidationError{Path: "images/foo/config.yml", Line: 12, Message: "did not find expected node content"},
+			expected: "images/foo/config.yml:12: did not find expected node content",
+		},
+		{
+			testName: "No position",
+			err:      &ValidationError{Path: "images/foo/config.yml", Message: "unable to find Dockerfile"},
+			expected: "images/foo/config.yml: unable to find Dockerfile",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.expected, tc.err.Error())
+		})
+	}
+}
+
+func TestValidationErrors_sorted(t *testing.T) {
+	problems := ValidationErrors{
+		{Path: "b/config.yml", Line: 1, Message: "first"},
+		{Path: "a/config.yml", Line: 5, Message: "second"},
+		{Path: "a/config.yml", Line: 2, Message: "third"},
+		{Path: "a/config.yml", Line: 2, Message: "fourth"},
+	}
+
+	require.Equal(t, "a/config.yml:2: third\na/config.yml:2: fourth\na/config.yml:5: second\nb/config.yml:1: first", problems.sorted().Error())
+	require.NoError(t, ValidationErrors{}.orNil())
+}
@@ -0,0 +1,190 @@
+package checker
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// findDefaultsFile returns the path of the nearest root defaults file in dir or its parents. The search stops at the
+// root of the git repo so that an unrelated defaults file outside of it is never used
+func findDefaultsFile(dir string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("resolving path %s: %w", dir, err)
+	}
+
+	for d := absDir; ; {
+		candidate := filepath.Join(d, defaultConfigFile)
+		if _, err = os.Stat(candidate); err == nil {
+			return candidate, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("checking for %s: %w", candidate, err)
+		}
+
+		if _, err = os.Stat(filepath.Join(d, ".git")); err == nil {
+			break
+		}
+
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+
+	return "", fmt.Errorf("no %s found in %s or its parent directories", defaultConfigFile, absDir)
+}
+
+// layeredDefaults returns the defaults of the image directory: the root defaults, overlaid by any defaults file in
+// the directories between the base image directory and the image directory, nearest last
+func (c *config) layeredDefaults(imageDirectory, imageDir string, root repoConfig) (repoConfig, error) {
+	rel, err := filepath.Rel(imageDirectory, imageDir)
+	if err != nil {
+		return root, fmt.Errorf("resolving %s relative to %s: %w", imageDir, imageDirectory, err)
+	}
+
+	defaults := root
+	for _, dir := range ancestorDirs(imageDirectory, filepath.ToSlash(rel)) {
+		layer, err := c.directoryDefaults(dir)
+		if err != nil {
+			return root, err
+		}
+		if layer != nil {
+			defaults = layerDefaults(defaults, *layer)
+		}
+	}
+
+	return defaults, nil
+}
+
+// errInvalidDefaults is returned for the images below a nested defaults file which failed to parse. The problems with
+// the file are only reported once, through defaultsProblems
+var errInvalidDefaults = errors.New("nested defaults file is invalid")
+
+// directoryDefaults parses the defaults file of dir, if it has one. Results, including failures, are cached as many
+// images share a parent
+func (c *config) directoryDefaults(dir string) (*repoConfig, error) {
+	if c.directoryDefaultsCache == nil {
+		c.directoryDefaultsCache = make(map[string]*repoConfig)
+	}
+
+	if c.invalidDefaultsDirs[dir] {
+		return nil, errInvalidDefaults
+	}
+
+	if layer, ok := c.directoryDefaultsCache[dir]; ok {
+		return layer, nil
+	}
+
+	p := filepath.Join(dir, defaultConfigFile)
+
+	// The root defaults file is often in the base image directory and has already been applied
+	if absPath, err := filepath.Abs(p); err == nil && absPath == c.rootDefaultsFile {
+		c.directoryDefaultsCache[dir] = nil
+		return nil, nil
+	}
+
+	layer, node, err := parseYAMLFile(p, c.allowUnknownFields)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			c.directoryDefaultsCache[dir] = nil
+			return nil, nil
+		}
+
+		var problems ValidationErrors
+		if !errors.As(err, &problems) {
+			return nil, err
+		}
+
+		if c.invalidDefaultsDirs == nil {
+			c.invalidDefaultsDirs = make(map[string]bool)
+		}
+		c.invalidDefaultsDirs[dir] = true
+		c.defaultsProblems = append(c.defaultsProblems, problems...)
+
+		return nil, errInvalidDefaults
+	}
+
+	slog.Info("Found nested defaults file", "path", p)
+	c.directoryDefaultsCache[dir] = &layer
+
+	if c.nodes == nil {
+		c.nodes = make(map[string]*yaml.Node)
+	}
+	c.nodes[p] = node
+	c.defaultsProblems = append(c.defaultsProblems, c.validateValues(p, layer)...)
+
+	return &layer, nil
+}
+
+// layerDefaults overlays the values set in over onto base. Every key can be defaulted: scalars and lists such as
+// target_platforms and targets are replaced when set, while build_args and environments are merged key by key so each
+// layer only lists those it changes. ignore isn't inherited as its globs are relative to the file which sets it
+func layerDefaults(base, over repoConfig) repoConfig {
+	base.DefaultAwsAccountId = cmp.Or(over.DefaultAwsAccountId, base.DefaultAwsAccountId)
+	base.DefaultRegion = cmp.Or(over.DefaultRegion, base.DefaultRegion)
+	base.DefaultAwsRoleName = cmp.Or(over.DefaultAwsRoleName, base.DefaultAwsRoleName)
+	base.RepoName = cmp.Or(over.RepoName, base.RepoName)
+	base.RepoTag = cmp.Or(over.RepoTag, base.RepoTag)
+	base.RepoTagStrategy = cmp.Or(over.RepoTagStrategy, base.RepoTagStrategy)
+
+	if len(over.RepoTags) > 0 {
+		base.RepoTags = over.RepoTags
+	}
+
+	if len(over.TargetPlatforms) > 0 {
+		base.TargetPlatforms = over.TargetPlatforms
+	}
+
+	// An empty build_args is kept, rather than treated as unset, so that it's still rejected by validation
+	if over.BuildArgs != nil {
+		merged := maps.Clone(base.BuildArgs)
+		if merged == nil {
+			merged = make(map[string]string, len(over.BuildArgs))
+		}
+		maps.Copy(merged, over.BuildArgs)
+		base.BuildArgs = merged
+	}
+
+	// Environments are merged by name, so a nested defaults file can add or replace an environment
+	if len(over.Environments) > 0 {
+		merged := maps.Clone(base.Environments)
+		if merged == nil {
+			merged = make(map[string][]*Target, len(over.Environments))
+		}
+		maps.Copy(merged, over.Environments)
+		base.Environments = merged
+	}
+
+	if len(over.Targets) > 0 {
+		base.Targets = over.Targets
+	} else {
+		// Targets are completed and calculated in place, so each image needs its own copy of inherited targets
+		base.Targets = cloneTargets(base.Targets)
+	}
+
+	base.Ignore = over.Ignore
+
+	return base
+}
+
+func cloneTargets(targets []*Target) []*Target {
+	if targets == nil {
+		return nil
+	}
+
+	cloned := make([]*Target, 0, len(targets))
+	for _, target := range targets {
+		t := *target
+		cloned = append(cloned, &t)
+	}
+
+	return cloned
+}
@@ -0,0 +1,288 @@
+package checker
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_findDefaultsFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"repo/config-defaults.yml":              "default_aws_region: eu-west-1",
+		"repo/images/image-1/config.yml":        "repo_name: image-1",
+		"repo/nested/.git/HEAD":                 "ref: refs/heads/main",
+		"repo/nested/images/image-1/config.yml": "repo_name: image-1",
+	})
+
+	result, err := findDefaultsFile(filepath.Join(dir, "repo", "images"))
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "repo", defaultConfigFile), result, "the nearest parent should be found")
+
+	result, err = findDefaultsFile(filepath.Join(dir, "repo"))
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "repo", defaultConfigFile), result)
+
+	_, err = findDefaultsFile(filepath.Join(dir, "repo", "nested", "images"))
+	require.Error(t, err, "the search should stop at the git repo root")
+}
+
+func Test_layeredDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"images/config-defaults.yml":                "default_aws_region: eu-west-2",
+		"images/team-a/config-defaults.yml":         "default_aws_account_id: \"222222222222\"",
+		"images/team-a/backend/config-defaults.yml": "default_aws_role_name: team-a-backend",
+		"images/team-b/config-defaults.yml":         "default_aws_region: [",
+	})
+	imageDirectory := filepath.Join(dir, "images")
+	root := repoConfig{
+		DefaultAwsAccountId: aws.String("111111111111"),
+		DefaultRegion:       aws.String("eu-west-1"),
+	}
+
+	c := newConfig()
+
+	result, err := c.layeredDefaults(imageDirectory, filepath.Join(imageDirectory, "team-a", "backend", "api"), root)
+	require.NoError(t, err)
+	require.Equal(t, "222222222222", *result.DefaultAwsAccountId)
+	require.Equal(t, "eu-west-2", *result.DefaultRegion)
+	require.Equal(t, "team-a-backend", *result.DefaultAwsRoleName)
+
+	result, err = c.layeredDefaults(imageDirectory, filepath.Join(imageDirectory, "team-a", "backend"), root)
+	require.NoError(t, err)
+	require.Nil(t, result.DefaultAwsRoleName, "the image directory's own defaults file isn't a layer")
+
+	result, err = c.layeredDefaults(imageDirectory, filepath.Join(imageDirectory, "image-1"), root)
+	require.NoError(t, err)
+	require.Equal(t, "111111111111", *result.DefaultAwsAccountId)
+	require.Equal(t, "eu-west-2", *result.DefaultRegion)
+	require.Equal(t, "111111111111", *root.DefaultAwsAccountId, "the root defaults must not be modified")
+	require.Equal(t, "eu-west-1", *root.DefaultRegion, "the root defaults must not be modified")
+
+	_, err = c.layeredDefaults(imageDirectory, filepath.Join(imageDirectory, "team-b", "image-1"), root)
+	require.ErrorIs(t, err, errInvalidDefaults)
+	require.Len(t, c.defaultsProblems, 1)
+	require.Equal(t, filepath.Join(imageDirectory, "team-b", defaultConfigFile), c.defaultsProblems[0].Path)
+
+	_, err = c.layeredDefaults(imageDirectory, filepath.Join(imageDirectory, "team-b", "image-2"), root)
+	require.ErrorIs(t, err, errInvalidDefaults)
+	require.Len(t, c.defaultsProblems, 1, "the problems of a defaults file should only be reported once")
+}
+
+func Test_loadConfig_defaults(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config-defaults.yml":       "default_aws_account_id: \"111111111111\"\ndefault_aws_region: eu-west-1\n",
+		"images/image-1/Dockerfile": "FROM alpine:3",
+		"images/image-1/config.yml": "repo_name: image-1\nrepo_tag: \"1\"\ntarget_platforms: [linux/amd64]\n",
+	})
+	imageDirectory := filepath.Join(dir, "images")
+	key := filepath.Join(imageDirectory, "image-1", childConfigFile)
+
+	// The working directory is the package directory, which has no defaults file
+	c, err := loadConfig(Options{ImageDirectory: imageDirectory, Concurrency: DefaultConcurrency})
+	require.NoError(t, err)
+	require.Equal(t, "eu-west-1", *c.repos[key].Targets[0].AwsRegion, "the root defaults should be found relative to the image directory")
+
+	writeFiles(t, dir, map[string]string{"images/config-defaults.yml": "default_aws_region: eu-west-2\n"})
+
+	c, err = loadConfig(Options{ImageDirectory: imageDirectory, DefaultsFile: filepath.Join(dir, defaultConfigFile), Concurrency: DefaultConcurrency})
+	require.NoError(t, err)
+	require.Equal(t, "111111111111", *c.repos[key].Targets[0].AwsAccountId)
+	require.Equal(t, "eu-west-2", *c.repos[key].Targets[0].AwsRegion, "nested defaults should layer over the root")
+}
+
+func Test_layerDefaults(t *testing.T) {
+	base := repoConfig{
+		DefaultAwsAccountId: aws.String("111111111111"),
+		DefaultRegion:       aws.String("eu-west-1"),
+		RepoTagStrategy:     aws.String(repoTagStrategyContentHash),
+		TargetPlatforms:     []string{"linux/amd64", "linux/arm64"},
+		BuildArgs:           map[string]string{"REGISTRY_MIRROR": "mirror.example.com", "BASE_TAG": "3.20"},
+		Targets:             []*Target{{AwsRegion: aws.String("eu-west-1")}},
+		Ignore:              []string{"legacy"},
+	}
+
+	cases := []struct {
+		testName string
+		over     repoConfig
+		check    func(t *testing.T, result repoConfig)
+	}{
+		{
+			testName: "Unset keys are inherited",
+			over:     repoConfig{RepoName: aws.String("image-1")},
+			check: func(t *testing.T, result repoConfig) {
+				require.Equal(t, "image-1", *result.RepoName)
+				require.Equal(t, "111111111111", *result.DefaultAwsAccountId)
+				require.Equal(t, repoTagStrategyContentHash, *result.RepoTagStrategy)
+				require.Equal(t, []string{"linux/amd64", "linux/arm64"}, result.TargetPlatforms)
+				require.Equal(t, base.BuildArgs, result.BuildArgs)
+				require.Equal(t, "eu-west-1", *result.Targets[0].AwsRegion)
+				require.NotSame(t, base.Targets[0], result.Targets[0], "inherited targets should be copied")
+				require.Nil(t, result.Ignore, "ignore shouldn't be inherited")
+			},
+		},
+		{
+			testName: "Scalars and target platforms are replaced",
+			over: repoConfig{
+				DefaultRegion:   aws.String("eu-west-2"),
+				RepoTagStrategy: aws.String(repoTagStrategyStatic),
+				TargetPlatforms: []string{"linux/amd64"},
+			},
+			check: func(t *testing.T, result repoConfig) {
+				require.Equal(t, "eu-west-2", *result.DefaultRegion)
+				require.Equal(t, repoTagStrategyStatic, *result.RepoTagStrategy)
+				require.Equal(t, []string{"linux/amd64"}, result.TargetPlatforms)
+			},
+		},
+		{
+			testName: "Build args are merged by key",
+			over:     repoConfig{BuildArgs: map[string]string{"BASE_TAG": "3.21", "EXTRA": "1"}},
+			check: func(t *testing.T, result repoConfig) {
+				require.Equal(t, map[string]string{"REGISTRY_MIRROR": "mirror.example.com", "BASE_TAG": "3.21", "EXTRA": "1"}, result.BuildArgs)
+			},
+		},
+		{
+			testName: "Targets are replaced",
+			over:     repoConfig{Targets: []*Target{{AwsRegion: aws.String("us-east-1")}}},
+			check: func(t *testing.T, result repoConfig) {
+				require.Len(t, result.Targets, 1)
+				require.Equal(t, "us-east-1", *result.Targets[0].AwsRegion)
+			},
+		},
+		{
+			testName: "Ignore of the overlaying file is kept",
+			over:     repoConfig{Ignore: []string{"old"}},
+			check: func(t *testing.T, result repoConfig) {
+				require.Equal(t, []string{"old"}, result.Ignore)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			tc.check(t, layerDefaults(base, tc.over))
+		})
+	}
+
+	require.Equal(t, map[string]string{"REGISTRY_MIRROR": "mirror.example.com", "BASE_TAG": "3.20"}, base.BuildArgs, "the base build args must not be modified")
+}
+
+func Test_loadConfig_allDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config-defaults.yml":        "repo_tag: \"1\"\ntarget_platforms: [linux/amd64]\nbuild_args:\n  MIRROR: mirror.example.com\ntargets:\n  - aws_account_id: \"111111111111\"\n    aws_region: eu-west-1\n",
+		"image-1/Dockerfile":         "FROM alpine:3",
+		"image-1/config.yml":         "repo_name: image-1\nbuild_args:\n  VERSION: \"1.0\"\n",
+		"image-2/Dockerfile":         "FROM alpine:3",
+		"image-2/config.yml":         "repo_name: image-2\ntarget_platforms: [linux/arm64]\n",
+		"team-a/config-defaults.yml": "build_args:\n  MIRROR: team-a.example.com\n",
+		"team-a/image-3/Dockerfile":  "FROM alpine:3",
+		"team-a/image-3/config.yml":  "repo_name: image-3\n",
+	})
+
+	c, err := loadConfig(Options{ImageDirectory: dir, Concurrency: DefaultConcurrency})
+	require.NoError(t, err)
+
+	image1 := c.repos[filepath.Join(dir, "image-1", childConfigFile)]
+	require.Equal(t, "1", *image1.RepoTag)
+	require.Equal(t, []string{"linux/amd64"}, image1.TargetPlatforms)
+	require.Equal(t, map[string]string{"MIRROR": "mirror.example.com", "VERSION": "1.0"}, image1.BuildArgs)
+	require.Equal(t, "111111111111.dkr.ecr.eu-west-1.amazonaws.com/image-1:1", image1.Targets[0].FullImageRef)
+
+	image2 := c.repos[filepath.Join(dir, "image-2", childConfigFile)]
+	require.Equal(t, []string{"linux/arm64"}, image2.TargetPlatforms)
+	require.Equal(t, "111111111111.dkr.ecr.eu-west-1.amazonaws.com/image-2:1", image2.Targets[0].FullImageRef, "inherited targets shouldn't be shared between images")
+
+	image3 := c.repos[filepath.Join(dir, "team-a", "image-3", childConfigFile)]
+	require.Equal(t, map[string]string{"MIRROR": "team-a.example.com"}, image3.BuildArgs)
+}
+
+func Test_loadConfig_childDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config-defaults.yml":        "default_aws_region: eu-west-2\n",
+		"image-1/Dockerfile":         "FROM alpine:3",
+		"image-1/config.yml":         "repo_name: image-1\nrepo_tag: \"1\"\ntarget_platforms: [linux/amd64]\ndefault_aws_account_id: \"111111111111\"\ntargets:\n  - aws_region: eu-west-1\n",
+		"team-a/config-defaults.yml": "default_aws_account_id: \"222222222222\"\ndefault_aws_role_name: deployer\n",
+		"team-a/image-2/Dockerfile":  "FROM alpine:3",
+		"team-a/image-2/config.yml":  "repo_name: image-2\nrepo_tag: \"1\"\ntarget_platforms: [linux/amd64]\n",
+	})
+
+	c, err := loadConfig(Options{ImageDirectory: dir, Concurrency: DefaultConcurrency})
+	require.NoError(t, err)
+
+	image1 := c.repos[filepath.Join(dir, "image-1", childConfigFile)]
+	require.Equal(t, "111111111111.dkr.ecr.eu-west-1.amazonaws.com/image-1:1", image1.Targets[0].FullImageRef, "the child's default account should fill its targets")
+
+	image2 := c.repos[filepath.Join(dir, "team-a", "image-2", childConfigFile)]
+	require.Len(t, image2.Targets, 1)
+	require.Equal(t, "222222222222.dkr.ecr.eu-west-2.amazonaws.com/image-2:1", image2.Targets[0].FullImageRef, "the fallback target should use the layered defaults")
+	require.Equal(t, "deployer", *image2.Targets[0].AwsRoleName)
+}
+
+func Test_loadConfig_emptyBuildArgs(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config-defaults.yml": "default_aws_account_id: \"111111111111\"\ndefault_aws_region: eu-west-1\n",
+		"image-1/Dockerfile":  "FROM alpine:3",
+		"image-1/config.yml":  "repo_name: image-1\nrepo_tag: \"1\"\ntarget_platforms: [linux/amd64]\nbuild_args: {}\n",
+	})
+
+	_, err := loadConfig(Options{ImageDirectory: dir, Concurrency: DefaultConcurrency})
+	var problems ValidationErrors
+	require.ErrorAs(t, err, &problems)
+	require.Equal(t, ValidationErrors{{
+		Path:    filepath.Join(dir, "image-1", childConfigFile),
+		Line:    4,
+		Column:  1,
+		Message: "build_args must have at one key/pair when defined",
+	}}, problems)
+}
+
+func Test_loadConfig_defaultsValues(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config-defaults.yml":        "default_aws_account_id: \"1234\"\ndefault_aws_region: eu-west-1\ntarget_platforms: [\"\"]\n",
+		"team-a/config-defaults.yml": "build_args:\n  MIRROR: \"\"\n",
+		"team-a/image-1/Dockerfile":  "FROM alpine:3",
+		"team-a/image-1/config.yml":  "repo_name: image-1\nrepo_tag: \"1\"\n",
+		"team-a/image-2/Dockerfile":  "FROM alpine:3",
+		"team-a/image-2/config.yml":  "repo_name: image-2\nrepo_tag: \"1\"\n",
+	})
+	rootDefaults := filepath.Join(dir, defaultConfigFile)
+	nestedDefaults := filepath.Join(dir, "team-a", defaultConfigFile)
+
+	_, err := loadConfig(Options{ImageDirectory: dir, Concurrency: DefaultConcurrency})
+	var problems ValidationErrors
+	require.ErrorAs(t, err, &problems)
+	require.Equal(t, ValidationErrors{
+		{Path: rootDefaults, Line: 1, Column: 1, Message: "default_aws_account_id 1234 must be a 12 digit AWS account ID"},
+		{Path: rootDefaults, Line: 3, Column: 20, Message: "target_platforms cannot contain empty values, index 0"},
+		{Path: nestedDefaults, Line: 2, Column: 3, Message: "build_args must have no empty values, key MIRROR"},
+	}, problems, "inherited values should be reported once against the defaults file which sets them")
+}
+
+func Test_loadConfig_invalidNestedDefaults(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config-defaults.yml":      "default_aws_account_id: \"111111111111\"\ndefault_aws_region: eu-west-1\n",
+		"team/config-defaults.yml": "target_platform: [x]\n",
+		"team/image-1/Dockerfile":  "FROM alpine:3",
+		"team/image-1/config.yml":  "repo_name: image-1\nrepo_tag: \"1\"\ntarget_platforms: [linux/amd64]\n",
+		"team/image-2/Dockerfile":  "FROM alpine:3",
+		"team/image-2/config.yml":  "repo_name: image-2\nrepo_tag: \"1\"\ntarget_platforms: [linux/amd64]\n",
+	})
+
+	_, err := loadConfig(Options{ImageDirectory: dir, Concurrency: DefaultConcurrency})
+	var problems ValidationErrors
+	require.ErrorAs(t, err, &problems)
+	require.Len(t, problems, 1, "the defaults file shared by both images should be reported once")
+	require.Equal(t, filepath.Join(dir, "team", defaultConfigFile), problems[0].Path)
+	require.Contains(t, problems[0].Message, "target_platform")
+}
@@ -0,0 +1,162 @@
+package checker
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// discoverConfigFiles returns the paths of the child config files at any depth under the image directory, in lexical
+// order. Hidden directories are skipped, as are the subdirectories of an image as they are part of its build context
+// and may contain unrelated config.yml files
+func discoverConfigFiles(imageDirectory string) ([]string, error) {
+	paths := make([]string, 0)
+
+	err := filepath.WalkDir(imageDirectory, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		// A config file in the base directory itself isn't an image, matching the original one level layout
+		if p == imageDirectory {
+			return nil
+		}
+
+		if strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		configPath := filepath.Join(p, childConfigFile)
+		if _, err = os.Stat(configPath); err != nil {
+			if !os.IsNotExist(err) {
+				return err
+			}
+
+			if _, err = os.Stat(filepath.Join(p, "Dockerfile")); err == nil {
+				slog.Warn("Skipping directory as child config file doesn't exist", "path", configPath)
+			}
+			return nil
+		}
+
+		paths = append(paths, configPath)
+
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("discovering config files in %s: %w", imageDirectory, err)
+	}
+
+	return paths, nil
+}
+
+// compileGlobs compiles the patterns using the .dockerignore syntax, so that ** and ! exceptions are supported and a
+// pattern matching a directory also matches everything within it. Nil is returned if there are no patterns
+func compileGlobs(patterns []string) (*dockerignore, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	return parseDockerignore(strings.NewReader(strings.Join(patterns, "\n")))
+}
+
+// selected reports whether the image directory passes the --include and --exclude filters and isn't ignored by the
+// ignore list of the root defaults, or of a nested defaults file above it
+func (c *config) selected(imageDirectory, imageDir string, rootIgnore *dockerignore) (bool, error) {
+	rel, err := filepath.Rel(imageDirectory, imageDir)
+	if err != nil {
+		return false, fmt.Errorf("resolving %s relative to %s: %w", imageDir, imageDirectory, err)
+	}
+	rel = filepath.ToSlash(rel)
+
+	// The matchers report a match through ignores, as they share the .dockerignore implementation
+	if c.include != nil && !c.include.ignores(rel) {
+		slog.Debug("Skipping image not matching --include", "path", imageDir)
+		return false, nil
+	}
+
+	if c.exclude.ignores(rel) {
+		slog.Debug("Skipping image matching --exclude", "path", imageDir)
+		return false, nil
+	}
+
+	// Defaults files ignore paths relative to their own directory, which for the root defaults isn't always --dir
+	if rootIgnore != nil {
+		rootDir := imageDirectory
+		if c.rootDefaultsFile != "" {
+			rootDir = filepath.Dir(c.rootDefaultsFile)
+		}
+
+		absImageDir, err := filepath.Abs(imageDir)
+		if err != nil {
+			return false, fmt.Errorf("resolving absolute path of %s: %w", imageDir, err)
+		}
+
+		absRootDir, err := filepath.Abs(rootDir)
+		if err != nil {
+			return false, fmt.Errorf("resolving absolute path of %s: %w", rootDir, err)
+		}
+
+		rootRel, err := filepath.Rel(absRootDir, absImageDir)
+		if err != nil {
+			return false, fmt.Errorf("resolving %s relative to %s: %w", imageDir, rootDir, err)
+		}
+
+		if rootIgnore.ignores(filepath.ToSlash(rootRel)) {
+			slog.Info("Skipping image ignored by the root defaults", "path", imageDir)
+			return false, nil
+		}
+	}
+
+	for _, dir := range ancestorDirs(imageDirectory, rel) {
+		layer, err := c.directoryDefaults(dir)
+		if err != nil {
+			return false, err
+		}
+		if layer == nil || len(layer.Ignore) == 0 {
+			continue
+		}
+
+		ignore, err := compileGlobs(layer.Ignore)
+		if err != nil {
+			return false, fmt.Errorf("compiling ignore patterns of %s: %w", filepath.Join(dir, defaultConfigFile), err)
+		}
+
+		layerRel, err := filepath.Rel(dir, imageDir)
+		if err != nil {
+			return false, fmt.Errorf("resolving %s relative to %s: %w", imageDir, dir, err)
+		}
+
+		if ignore.ignores(filepath.ToSlash(layerRel)) {
+			slog.Info("Skipping image ignored by nested defaults", "path", imageDir, "defaults", filepath.Join(dir, defaultConfigFile))
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// ancestorDirs returns the base image directory and each directory below it down to, but not including, the image
+// directory at the slash separated rel path
+func ancestorDirs(imageDirectory, rel string) []string {
+	dirs := []string{imageDirectory}
+
+	parent := filepath.Dir(filepath.FromSlash(rel))
+	if parent == "." || rel == "." {
+		return dirs
+	}
+
+	d := imageDirectory
+	for _, part := range strings.Split(filepath.ToSlash(parent), "/") {
+		d = filepath.Join(d, part)
+		dirs = append(dirs, d)
+	}
+
+	return dirs
+}
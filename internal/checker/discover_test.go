@@ -0,0 +1,146 @@
+package checker
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_discoverConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.yml":                         "repo_name: not-an-image",
+		"image-1/config.yml":                 "repo_name: image-1",
+		"image-1/tests/config.yml":           "repo_name: build-context",
+		"team-a/backend/api/config.yml":      "repo_name: api",
+		"team-a/backend/worker/config.yml":   "repo_name: worker",
+		"team-a/frontend/config.yml":         "repo_name: frontend",
+		"team-b/missing-config/Dockerfile":   "FROM alpine:3",
+		".github/workflows/image/config.yml": "repo_name: hidden",
+	})
+
+	result, err := discoverConfigFiles(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "image-1", childConfigFile),
+		filepath.Join(dir, "team-a", "backend", "api", childConfigFile),
+		filepath.Join(dir, "team-a", "backend", "worker", childConfigFile),
+		filepath.Join(dir, "team-a", "frontend", childConfigFile),
+	}, result)
+
+	_, err = discoverConfigFiles(filepath.Join(dir, "missing"))
+	require.Error(t, err)
+}
+
+func Test_selected(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"team-b/config-defaults.yml": "ignore:\n  - legacy\n  - deprecated/**\n",
+		"team-c/config-defaults.yml": "ignore: [",
+	})
+
+	cases := []struct {
+		testName   string
+		include    []string
+		exclude    []string
+		rootIgnore []string
+		imageDir   string
+		expected   bool
+	}{
+		{testName: "No filters", imageDir: "team-a/api", expected: true},
+		{testName: "Included directory", include: []string{"team-a"}, imageDir: "team-a/api", expected: true},
+		{testName: "Included glob", include: []string{"team-*/api"}, imageDir: "team-a/api", expected: true},
+		{testName: "Not included", include: []string{"team-a"}, imageDir: "team-b/api", expected: false},
+		{testName: "Excluded", exclude: []string{"**/api"}, imageDir: "team-a/api", expected: false},
+		{testName: "Exclude wins over include", include: []string{"team-a"}, exclude: []string{"team-a/api"}, imageDir: "team-a/api", expected: false},
+		{testName: "Exclude exception", exclude: []string{"team-a", "!team-a/api"}, imageDir: "team-a/api", expected: true},
+		{testName: "Ignored by the root defaults", rootIgnore: []string{"team-a/api"}, imageDir: "team-a/api", expected: false},
+		{testName: "Ignored by nested defaults", imageDir: "team-b/legacy", expected: false},
+		{testName: "Ignored by nested defaults glob", imageDir: "team-b/deprecated/api", expected: false},
+		{testName: "Nested ignore is relative to its directory", imageDir: "legacy", expected: true},
+		{testName: "Not ignored by nested defaults", imageDir: "team-b/api", expected: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			var err error
+			c := newConfig()
+			c.include, err = compileGlobs(tc.include)
+			require.NoError(t, err)
+			c.exclude, err = compileGlobs(tc.exclude)
+			require.NoError(t, err)
+			rootIgnore, err := compileGlobs(tc.rootIgnore)
+			require.NoError(t, err)
+
+			result, err := c.selected(dir, filepath.Join(dir, filepath.FromSlash(tc.imageDir)), rootIgnore)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, result)
+		})
+	}
+
+	c := newConfig()
+	_, err := c.selected(dir, filepath.Join(dir, "team-c", "api"), nil)
+	require.ErrorIs(t, err, errInvalidDefaults, "a broken nested defaults file should be reported")
+	require.NotEmpty(t, c.defaultsProblems)
+}
+
+func Test_ancestorDirs(t *testing.T) {
+	require.Equal(t, []string{"images"}, ancestorDirs("images", "image-1"))
+	require.Equal(t, []string{"images"}, ancestorDirs("images", "."))
+	require.Equal(t, []string{
+		"images",
+		filepath.Join("images", "team-a"),
+		filepath.Join("images", "team-a", "backend"),
+	}, ancestorDirs("images", "team-a/backend/api"))
+}
+
+func Test_loadConfig_discovery(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config-defaults.yml":             "default_aws_account_id: \"111111111111\"\ndefault_aws_region: eu-west-1\nignore:\n  - team-a/old\n",
+		"team-a/api/Dockerfile":           "FROM alpine:3",
+		"team-a/api/config.yml":           "repo_name: api\nrepo_tag: \"1\"\ntarget_platforms: [linux/amd64]\n",
+		"team-a/old/Dockerfile":           "FROM alpine:3",
+		"team-a/old/config.yml":           "repo_name: [",
+		"team-b/worker/Dockerfile":        "FROM alpine:3",
+		"team-b/worker/config.yml":        "repo_name: worker\nrepo_tag: \"1\"\ntarget_platforms: [linux/amd64]\n",
+		"team-b/misplaced/Dockerfile":     "FROM alpine:3",
+		"team-b/misplaced/config.yml":     "repo_name: misplaced\nrepo_tag: \"1\"\ntarget_platforms: [linux/amd64]\nignore: [other]\n",
+		"team-b/misplaced/src/config.yml": "not: an image",
+	})
+
+	c, err := loadConfig(Options{ImageDirectory: dir, Exclude: []string{"team-b/misplaced"}, Concurrency: DefaultConcurrency})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "team-a", "api", childConfigFile),
+		filepath.Join(dir, "team-b", "worker", childConfigFile),
+	}, sortedKeys(c.repos))
+
+	_, err = loadConfig(Options{ImageDirectory: dir, Include: []string{"team-b/misplaced"}, Concurrency: DefaultConcurrency})
+	var problems ValidationErrors
+	require.ErrorAs(t, err, &problems)
+	require.Equal(t, ValidationErrors{
+		{Path: filepath.Join(dir, "team-b", "misplaced", childConfigFile), Line: 4, Column: 1, Message: "ignore is only supported in config-defaults.yml"},
+	}, problems)
+
+	_, err = loadConfig(Options{ImageDirectory: dir, Include: []string{"["}, Concurrency: DefaultConcurrency})
+	require.Error(t, err, "an invalid glob should be rejected")
+}
+
+func Test_loadConfig_rootIgnoreFromParent(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config-defaults.yml":      "default_aws_account_id: \"111111111111\"\ndefault_aws_region: eu-west-1\nignore:\n  - images/legacy\n",
+		"images/api/Dockerfile":    "FROM alpine:3",
+		"images/api/config.yml":    "repo_name: api\nrepo_tag: \"1\"\ntarget_platforms: [linux/amd64]\n",
+		"images/legacy/Dockerfile": "FROM alpine:3",
+		"images/legacy/config.yml": "repo_name: [",
+	})
+
+	c, err := loadConfig(Options{ImageDirectory: filepath.Join(dir, "images"), Concurrency: DefaultConcurrency})
+	require.NoError(t, err, "the root ignore globs should be relative to the defaults file, not --dir")
+	require.Equal(t, []string{filepath.Join(dir, "images", "api", childConfigFile)}, sortedKeys(c.repos))
+}
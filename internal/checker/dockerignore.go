@@ -0,0 +1,195 @@
+package checker
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const dockerignoreFile = ".dockerignore"
+
+// dockerignore matches build context paths against the patterns of a .dockerignore file, using the same semantics as
+// Docker: patterns are matched against the path or any of its parent directories, ** matches any number of
+// directories, and the last matching pattern wins so ! exceptions can re-include paths
+type dockerignore struct {
+	patterns []ignorePattern
+}
+
+type ignorePattern struct {
+	exclusion bool
+	re        *regexp.Regexp
+}
+
+// readDockerignore parses the .dockerignore file of dir. A nil matcher is returned if there isn't one
+func readDockerignore(dir string) (*dockerignore, error) {
+	f, err := os.Open(filepath.Join(dir, dockerignoreFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("opening %s: %w", dockerignoreFile, err)
+	}
+	defer f.Close()
+
+	d, err := parseDockerignore(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s in %s: %w", dockerignoreFile, dir, err)
+	}
+
+	return d, nil
+}
+
+func parseDockerignore(r io.Reader) (*dockerignore, error) {
+	d := &dockerignore{}
+	utf8BOM := []byte{0xEF, 0xBB, 0xBF}
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Bytes()
+		if lineNo == 1 {
+			line = bytes.TrimPrefix(line, utf8BOM)
+		}
+
+		// Comments must start at the beginning of the line
+		if len(line) > 0 && line[0] == '#' {
+			continue
+		}
+
+		pattern := strings.TrimSpace(string(line))
+		if pattern == "" {
+			continue
+		}
+
+		exclusion := strings.HasPrefix(pattern, "!")
+		if exclusion {
+			pattern = strings.TrimSpace(pattern[1:])
+			if pattern == "" {
+				return nil, fmt.Errorf("line %d: illegal exclusion pattern %q", lineNo, "!")
+			}
+		}
+
+		pattern = path.Clean(filepath.ToSlash(pattern))
+		if len(pattern) > 1 && pattern[0] == '/' {
+			pattern = pattern[1:]
+		}
+
+		re, err := compileIgnorePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern %q: %w", lineNo, pattern, err)
+		}
+
+		d.patterns = append(d.patterns, ignorePattern{exclusion: exclusion, re: re})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading patterns: %w", err)
+	}
+
+	return d, nil
+}
+
+// ignores reports whether the slash separated path, relative to the build context, is excluded
+func (d *dockerignore) ignores(rel string) bool {
+	if d == nil {
+		return false
+	}
+
+	parentDirs := strings.Split(path.Dir(rel), "/")
+	ignored := false
+
+	for _, p := range d.patterns {
+		// Only patterns which could flip the current result need checking
+		if p.exclusion != ignored {
+			continue
+		}
+
+		match := p.re.MatchString(rel)
+		if !match && parentDirs[0] != "." {
+			for i := range parentDirs {
+				if p.re.MatchString(strings.Join(parentDirs[:i+1], "/")) {
+					match = true
+					break
+				}
+			}
+		}
+
+		if match {
+			ignored = !p.exclusion
+		}
+	}
+
+	return ignored
+}
+
+// hasExclusions reports whether there are any ! patterns, in which case ignored directories must still be walked
+func (d *dockerignore) hasExclusions() bool {
+	if d == nil {
+		return false
+	}
+
+	for _, p := range d.patterns {
+		if p.exclusion {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compileIgnorePattern converts a Docker ignore pattern into an anchored regexp
+func compileIgnorePattern(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+
+	inClass := false
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+
+		switch {
+		case ch == '*' && i+1 < len(runes) && runes[i+1] == '*':
+			i++
+			// Treat **/ as **
+			if i+1 < len(runes) && runes[i+1] == '/' {
+				i++
+			}
+			if i+1 == len(runes) {
+				sb.WriteString(".*")
+			} else {
+				sb.WriteString("(.*/)?")
+			}
+		case ch == '*':
+			sb.WriteString("[^/]*")
+		case ch == '?':
+			sb.WriteString("[^/]")
+		case ch == '\\':
+			if i+1 < len(runes) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(runes[i])))
+			} else {
+				sb.WriteString(`\\`)
+			}
+		case ch == '[':
+			inClass = true
+			sb.WriteRune(ch)
+		case ch == ']':
+			inClass = false
+			sb.WriteRune(ch)
+		case inClass && (ch == '^' || ch == '-'):
+			// Negation and ranges within character classes are passed through as-is
+			sb.WriteRune(ch)
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	sb.WriteString("$")
+
+	return regexp.Compile(sb.String())
+}
@@ -0,0 +1,90 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_dockerignore_ignores(t *testing.T) {
+	cases := []struct {
+		testName string
+		patterns string
+		path     string
+		expected bool
+	}{
+		{testName: "Exact file", patterns: "notes.md", path: "notes.md", expected: true},
+		{testName: "Leading slash is relative to the context", patterns: "/notes.md", path: "notes.md", expected: true},
+		{testName: "Wildcard does not cross directories", patterns: "*.md", path: "docs/readme.md", expected: false},
+		{testName: "Wildcard in the root", patterns: "*.md", path: "readme.md", expected: true},
+		{testName: "Children of an ignored directory", patterns: "tests", path: "tests/fixtures/data.json", expected: true},
+		{testName: "Double star matches any depth", patterns: "**/*.md", path: "a/b/c/readme.md", expected: true},
+		{testName: "Double star matches zero directories", patterns: "**/*.md", path: "readme.md", expected: true},
+		{testName: "Double star in the middle", patterns: "src/**/testdata", path: "src/a/b/testdata/file", expected: true},
+		{testName: "Trailing double star", patterns: "src/**", path: "src/main.go", expected: true},
+		{testName: "Question mark", patterns: "file?.txt", path: "file1.txt", expected: true},
+		{testName: "Character class", patterns: "file[0-9].txt", path: "file5.txt", expected: true},
+		{testName: "Negated character class", patterns: "file[^0-9].txt", path: "file5.txt", expected: false},
+		{testName: "Dots are literal", patterns: "a.txt", path: "abtxt", expected: false},
+		{testName: "Escaped wildcard", patterns: `\*.txt`, path: "*.txt", expected: true},
+		{testName: "Exception re-includes a file", patterns: "*.md\n!readme.md", path: "readme.md", expected: false},
+		{testName: "Last match wins", patterns: "*.md\n!readme.md\nreadme.md", path: "readme.md", expected: true},
+		{testName: "Exception within an ignored directory", patterns: "tests\n!tests/keep.json", path: "tests/keep.json", expected: false},
+		{testName: "Comments are ignored", patterns: "# notes.md", path: "# notes.md", expected: false},
+		{testName: "Surrounding whitespace is trimmed", patterns: "  notes.md  ", path: "notes.md", expected: true},
+		{testName: "Paths are cleaned", patterns: "./tests/../build", path: "build/out", expected: true},
+		{testName: "Unmatched path", patterns: "tests", path: "src/main.go", expected: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			d, err := parseDockerignore(strings.NewReader(tc.patterns))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, d.ignores(tc.path))
+		})
+	}
+}
+
+func Test_parseDockerignore(t *testing.T) {
+	d, err := parseDockerignore(strings.NewReader("\xEF\xBB\xBFnotes.md\n\n# comment\n!keep.md"))
+	require.NoError(t, err)
+	require.Len(t, d.patterns, 2, "BOM, blank lines and comments should be stripped")
+	require.True(t, d.ignores("notes.md"))
+	require.True(t, d.hasExclusions())
+
+	_, err = parseDockerignore(strings.NewReader("!"))
+	require.Error(t, err)
+
+	_, err = parseDockerignore(strings.NewReader("[a-"))
+	require.Error(t, err)
+}
+
+func Test_dockerignore_nil(t *testing.T) {
+	var d *dockerignore
+	require.False(t, d.ignores("anything"))
+	require.False(t, d.hasExclusions())
+
+	d, err := readDockerignore(t.TempDir())
+	require.NoError(t, err)
+	require.Nil(t, d, "a missing .dockerignore should not ignore anything")
+}
+
+func Test_contextFiles_exceptions(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"Dockerfile":           "FROM alpine:3",
+		"tests/fixture.json":   "{}",
+		"tests/keep/data.json": "{}",
+		dockerignoreFile:       "tests\n!tests/keep\n",
+	})
+
+	d, err := readDockerignore(dir)
+	require.NoError(t, err)
+
+	files, err := contextFiles(dir, d)
+	require.NoError(t, err)
+	require.Equal(t, []string{dockerignoreFile, "Dockerfile", "tests/keep/data.json"}, files)
+}
@@ -0,0 +1,272 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrTypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/aws/smithy-go"
+)
+
+// ecrAPI is the subset of the ECR client used by ecrRegistry
+type ecrAPI interface {
+	ecr.ListImagesAPIClient
+	ecr.DescribeImagesAPIClient
+	BatchGetImage(ctx context.Context, params *ecr.BatchGetImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error)
+	GetDownloadUrlForLayer(ctx context.Context, params *ecr.GetDownloadUrlForLayerInput, optFns ...func(*ecr.Options)) (*ecr.GetDownloadUrlForLayerOutput, error)
+}
+
+// ecrDescribeImagesMaxIDs is the maximum number of image IDs which can be passed in a single DescribeImages call
+const ecrDescribeImagesMaxIDs = 100
+
+// ecrBlobClient downloads blobs from the pre-signed URLs returned by ECR, which need no further credentials
+var ecrBlobClient = &http.Client{Timeout: ociRequestTimeout}
+
+// ecrRegistry is the private AWS ECR implementation of Registry
+type ecrRegistry struct {
+	client ecrAPI
+
+	// registryID is only set when not assuming an IAM role, so we query the remote ECR registry directly
+	registryID *string
+}
+
+func newECRRegistry(clients *awsClientCache, target Target, repoName string) *ecrRegistry {
+	client, registryID := clients.ecrClient(target, repoName)
+
+	return &ecrRegistry{
+		client:     client,
+		registryID: registryID,
+	}
+}
+
+func (r *ecrRegistry) TagExists(ctx context.Context, repoName, tag string) (bool, error) {
+	found, err := r.TagsExist(ctx, repoName, []string{tag})
+	if err != nil {
+		return false, err
+	}
+
+	return found[tag], nil
+}
+
+// TagsExist looks up the tags directly using DescribeImages, batching them into as few calls as possible.
+// If the credentials are only permitted to use ecr:ListImages it falls back to paging through the repo
+func (r *ecrRegistry) TagsExist(ctx context.Context, repoName string, tags []string) (map[string]bool, error) {
+	found := make(map[string]bool, len(tags))
+
+	for chunk := range slices.Chunk(tags, ecrDescribeImagesMaxIDs) {
+		if err := r.describeImageTags(ctx, repoName, chunk, found); err != nil {
+			if isAccessDenied(err) {
+				slog.Debug("DescribeImages not permitted. Falling back to ListImages", "repo", repoName, "err", err)
+				return r.listImageTags(ctx, repoName, tags)
+			}
+			return nil, err
+		}
+	}
+
+	return found, nil
+}
+
+func (r *ecrRegistry) ListTags(ctx context.Context, repoName string) ([]string, error) {
+	tags := make([]string, 0)
+
+	err := r.listImages(ctx, repoName, func(imageTag string) bool {
+		tags = append(tags, imageTag)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (r *ecrRegistry) DescribeManifest(ctx context.Context, repoName, tag string) (Manifest, error) {
+	output, err := r.client.BatchGetImage(ctx, &ecr.BatchGetImageInput{
+		RepositoryName:     aws.String(repoName),
+		RegistryId:         r.registryID,
+		ImageIds:           []ecrTypes.ImageIdentifier{{ImageTag: aws.String(tag)}},
+		AcceptedMediaTypes: acceptedManifestMediaTypes,
+	})
+	if err != nil {
+		return Manifest{}, fmt.Errorf("getting ECR image %s:%s: %w", repoName, tag, err)
+	}
+
+	for _, failure := range output.Failures {
+		if failure.FailureCode == ecrTypes.ImageFailureCodeImageTagDoesNotMatchDigest ||
+			failure.FailureCode == ecrTypes.ImageFailureCodeImageNotFound {
+			return Manifest{}, fmt.Errorf("%s:%s: %w", repoName, tag, ErrManifestNotFound)
+		}
+		return Manifest{}, fmt.Errorf("getting ECR image %s:%s: %s", repoName, tag, aws.ToString(failure.FailureReason))
+	}
+
+	if len(output.Images) == 0 || output.Images[0].ImageManifest == nil {
+		return Manifest{}, fmt.Errorf("%s:%s: %w", repoName, tag, ErrManifestNotFound)
+	}
+
+	image := output.Images[0]
+	var digest string
+	if image.ImageId != nil {
+		digest = aws.ToString(image.ImageId.ImageDigest)
+	}
+
+	return parseManifest(aws.ToString(image.ImageManifestMediaType), digest, []byte(*image.ImageManifest))
+}
+
+// ConfigPlatform returns the platform of an image from its config blob, downloaded via a pre-signed layer URL
+func (r *ecrRegistry) ConfigPlatform(ctx context.Context, repoName, digest string) (string, error) {
+	output, err := r.client.GetDownloadUrlForLayer(ctx, &ecr.GetDownloadUrlForLayerInput{
+		RepositoryName: aws.String(repoName),
+		RegistryId:     r.registryID,
+		LayerDigest:    aws.String(digest),
+	})
+	if err != nil {
+		return "", fmt.Errorf("getting download URL for %s@%s: %w", repoName, digest, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, aws.ToString(output.DownloadUrl), nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := ecrBlobClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("downloading %s@%s: %w", repoName, digest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("downloading %s@%s: unexpected status %s", repoName, digest, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading %s@%s: %w", repoName, digest, err)
+	}
+
+	return parseConfigPlatform(body)
+}
+
+// describeImageTags records whether each tag exists in found. DescribeImages fails the whole call with an
+// ImageNotFoundException if any of the tags are missing, so in that case each tag is looked up individually
+func (r *ecrRegistry) describeImageTags(ctx context.Context, repoName string, tags []string, found map[string]bool) error {
+	imageIDs := make([]ecrTypes.ImageIdentifier, 0, len(tags))
+	for _, tag := range tags {
+		imageIDs = append(imageIDs, ecrTypes.ImageIdentifier{ImageTag: aws.String(tag)})
+	}
+
+	output, err := r.client.DescribeImages(ctx, &ecr.DescribeImagesInput{
+		RepositoryName: aws.String(repoName),
+		RegistryId:     r.registryID,
+		ImageIds:       imageIDs,
+	})
+	if err != nil {
+		var notFound *ecrTypes.ImageNotFoundException
+		if !errors.As(err, &notFound) {
+			return fmt.Errorf("describing ECR images for %s: %w", repoName, err)
+		}
+
+		if len(tags) == 1 {
+			found[tags[0]] = false
+			return nil
+		}
+
+		for _, tag := range tags {
+			if err = r.describeImageTags(ctx, repoName, []string{tag}, found); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, tag := range tags {
+		found[tag] = false
+	}
+	for _, detail := range output.ImageDetails {
+		for _, imageTag := range detail.ImageTags {
+			if _, ok := found[imageTag]; ok {
+				slog.Debug("Found image tag", "repo", repoName, "tag", imageTag)
+				found[imageTag] = true
+			}
+		}
+	}
+
+	return nil
+}
+
+// listImageTags records whether each tag exists by paging through every tagged image in the repo
+func (r *ecrRegistry) listImageTags(ctx context.Context, repoName string, tags []string) (map[string]bool, error) {
+	found := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		found[tag] = false
+	}
+	remaining := len(tags)
+
+	err := r.listImages(ctx, repoName, func(imageTag string) bool {
+		if seen, ok := found[imageTag]; ok && !seen {
+			slog.Debug("Found image tag", "repo", repoName, "tag", imageTag)
+			found[imageTag] = true
+			remaining--
+		}
+		return remaining > 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// listImages pages through the tagged images in the repo, calling fn for each tag until it returns false
+func (r *ecrRegistry) listImages(ctx context.Context, repoName string, fn func(imageTag string) bool) error {
+	nextToken := ""
+
+	for {
+		listImagesInput := &ecr.ListImagesInput{
+			RepositoryName: aws.String(repoName),
+			RegistryId:     r.registryID,
+			Filter:         &ecrTypes.ListImagesFilter{TagStatus: ecrTypes.TagStatusTagged},
+		}
+
+		if nextToken != "" {
+			listImagesInput.NextToken = aws.String(nextToken)
+		}
+
+		ecrImages, err := r.client.ListImages(ctx, listImagesInput)
+		if err != nil {
+			return fmt.Errorf("listing ECR Docker tags for %s: %w", repoName, err)
+		}
+
+		// No more remote images to check
+		if ecrImages == nil {
+			return nil
+		}
+
+		for _, image := range ecrImages.ImageIds {
+			if image.ImageTag == nil {
+				continue
+			}
+			if !fn(*image.ImageTag) {
+				return nil
+			}
+		}
+
+		if ecrImages.NextToken == nil {
+			return nil
+		}
+
+		nextToken = *ecrImages.NextToken
+	}
+}
+
+// isAccessDenied reports whether the AWS API call was rejected by IAM
+func isAccessDenied(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDeniedException"
+}
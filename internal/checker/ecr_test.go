@@ -0,0 +1,256 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrTypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/require"
+)
+
+// mockECRImages are the tags returned by mockECRClient.DescribeImages
+var mockECRImages = map[string][]string{
+	"repo-1": {"v1", "v2"},
+	"repo-2": {"v1", "v2"},
+}
+
+type mockECRClient struct {
+	describeImagesCalls *atomic.Int32
+
+	// blobURL is returned as the download URL of every layer
+	blobURL string
+}
+
+func (m mockECRClient) ListImages(_ context.Context, input *ecr.ListImagesInput, _ ...func(*ecr.Options)) (*ecr.ListImagesOutput, error) {
+	var output ecr.ListImagesOutput
+
+	if input.RepositoryName != nil {
+		if *input.RepositoryName == "repo-1" {
+			output = ecr.ListImagesOutput{
+				ImageIds: []ecrTypes.ImageIdentifier{
+					{ImageTag: aws.String("v1")},
+					{ImageTag: aws.String("v2")},
+				},
+			}
+		}
+
+		if *input.RepositoryName == "repo-2" {
+			if input.NextToken != nil && *input.NextToken == "token" {
+				output = ecr.ListImagesOutput{
+					ImageIds: []ecrTypes.ImageIdentifier{
+						{ImageTag: aws.String("v2")},
+					},
+					NextToken: nil,
+				}
+				return &output, nil
+			}
+			output = ecr.ListImagesOutput{
+				ImageIds: []ecrTypes.ImageIdentifier{
+					{ImageTag: aws.String("v1")},
+				},
+				NextToken: aws.String("token"),
+			}
+		}
+
+		if *input.RepositoryName == "error-repo" {
+			return nil, errors.New("access denied")
+		}
+
+		if *input.RepositoryName == "list-only-repo" {
+			output = ecr.ListImagesOutput{
+				ImageIds: []ecrTypes.ImageIdentifier{
+					{ImageTag: aws.String("v1")},
+					{ImageTag: aws.String("v3")},
+				},
+			}
+		}
+	}
+
+	return &output, nil
+}
+
+func (m mockECRClient) DescribeImages(_ context.Context, input *ecr.DescribeImagesInput, _ ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error) {
+	if m.describeImagesCalls != nil {
+		m.describeImagesCalls.Add(1)
+	}
+
+	switch *input.RepositoryName {
+	case "error-repo":
+		return nil, errors.New("throttled")
+	case "list-only-repo":
+		return nil, &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "not authorized to perform ecr:DescribeImages"}
+	}
+
+	output := &ecr.DescribeImagesOutput{}
+	for _, id := range input.ImageIds {
+		if !slices.Contains(mockECRImages[*input.RepositoryName], *id.ImageTag) {
+			return nil, &ecrTypes.ImageNotFoundException{Message: aws.String("image not found")}
+		}
+		output.ImageDetails = append(output.ImageDetails, ecrTypes.ImageDetail{ImageTags: []string{*id.ImageTag}})
+	}
+
+	return output, nil
+}
+
+func (m mockECRClient) BatchGetImage(_ context.Context, input *ecr.BatchGetImageInput, _ ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error) {
+	if *input.RepositoryName == "repo-1" && *input.ImageIds[0].ImageTag == "v1" {
+		return &ecr.BatchGetImageOutput{
+			Images: []ecrTypes.Image{
+				{
+					ImageId:                &ecrTypes.ImageIdentifier{ImageDigest: aws.String("sha256:123"), ImageTag: aws.String("v1")},
+					ImageManifestMediaType: aws.String(mediaTypeOCIIndex),
+					ImageManifest:          aws.String(`{"manifests": [{"platform": {"os": "linux", "architecture": "amd64"}}]}`),
+				},
+			},
+		}, nil
+	}
+
+	return &ecr.BatchGetImageOutput{
+		Failures: []ecrTypes.ImageFailure{
+			{
+				FailureCode:   ecrTypes.ImageFailureCodeImageNotFound,
+				FailureReason: aws.String("Requested image not found"),
+			},
+		},
+	}, nil
+}
+
+func (m mockECRClient) GetDownloadUrlForLayer(_ context.Context, input *ecr.GetDownloadUrlForLayerInput, _ ...func(*ecr.Options)) (*ecr.GetDownloadUrlForLayerOutput, error) {
+	if *input.RepositoryName == "list-only-repo" {
+		return nil, &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "not authorized to perform ecr:GetDownloadUrlForLayer"}
+	}
+
+	return &ecr.GetDownloadUrlForLayerOutput{DownloadUrl: aws.String(m.blobURL + "/" + *input.LayerDigest), LayerDigest: input.LayerDigest}, nil
+}
+
+func Test_ecrRegistry_TagExists(t *testing.T) {
+	cases := []struct {
+		testName       string
+		repoName       string
+		tag            string
+		expectTagFound bool
+		expectError    bool
+	}{
+		{
+			testName:       "Docker tag found",
+			repoName:       "repo-1",
+			tag:            "v2",
+			expectTagFound: true,
+		},
+		{
+			testName:       "Docker tag missing",
+			repoName:       "repo-1",
+			tag:            "missing",
+			expectTagFound: false,
+		},
+		{
+			testName:       "Paginated results",
+			repoName:       "repo-2",
+			tag:            "v2",
+			expectTagFound: true,
+		},
+		{
+			testName:       "Fall back to ListImages when DescribeImages is denied",
+			repoName:       "list-only-repo",
+			tag:            "v3",
+			expectTagFound: true,
+		},
+		{
+			testName:    "API error",
+			repoName:    "error-repo",
+			tag:         "v1",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			r := &ecrRegistry{client: mockECRClient{}}
+
+			found, err := r.TagExists(context.Background(), tc.repoName, tc.tag)
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectTagFound, found)
+		})
+	}
+}
+
+func Test_ecrRegistry_TagsExist(t *testing.T) {
+	var calls atomic.Int32
+	r := &ecrRegistry{client: mockECRClient{describeImagesCalls: &calls}}
+
+	found, err := r.TagsExist(context.Background(), "repo-1", []string{"v1", "v2"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"v1": true, "v2": true}, found)
+	require.Equal(t, int32(1), calls.Load(), "tags should be batched into a single call")
+
+	found, err = r.TagsExist(context.Background(), "repo-1", []string{"v1", "missing", "v2"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"v1": true, "missing": false, "v2": true}, found)
+
+	found, err = r.TagsExist(context.Background(), "list-only-repo", []string{"v1", "v2"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"v1": true, "v2": false}, found)
+
+	_, err = r.TagsExist(context.Background(), "error-repo", []string{"v1"})
+	require.Error(t, err)
+}
+
+func Test_ecrRegistry_ListTags(t *testing.T) {
+	r := &ecrRegistry{client: mockECRClient{}}
+
+	tags, err := r.ListTags(context.Background(), "repo-2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1", "v2"}, tags)
+
+	_, err = r.ListTags(context.Background(), "error-repo")
+	require.Error(t, err)
+}
+
+func Test_ecrRegistry_DescribeManifest(t *testing.T) {
+	r := &ecrRegistry{client: mockECRClient{}}
+
+	m, err := r.DescribeManifest(context.Background(), "repo-1", "v1")
+	require.NoError(t, err)
+	require.Equal(t, "sha256:123", m.Digest)
+	require.Equal(t, []string{"linux/amd64"}, m.Platforms)
+
+	_, err = r.DescribeManifest(context.Background(), "repo-1", "missing")
+	require.ErrorIs(t, err, ErrManifestNotFound)
+}
+
+func Test_ecrRegistry_ConfigPlatform(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sha256:config" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"architecture": "arm64", "os": "linux"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	r := &ecrRegistry{client: mockECRClient{blobURL: server.URL}}
+
+	platform, err := r.ConfigPlatform(context.Background(), "repo-1", "sha256:config")
+	require.NoError(t, err)
+	require.Equal(t, "linux/arm64", platform)
+
+	_, err = r.ConfigPlatform(context.Background(), "repo-1", "sha256:missing")
+	require.Error(t, err)
+
+	_, err = r.ConfigPlatform(context.Background(), "list-only-repo", "sha256:config")
+	require.True(t, isAccessDenied(err))
+}
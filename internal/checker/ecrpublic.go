@@ -0,0 +1,137 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecrpublic"
+)
+
+const (
+	ecrPublicHost = "public.ecr.aws"
+
+	// The ECR Public API is only available in us-east-1
+	ecrPublicRegion = "us-east-1"
+)
+
+// ecrPublicRegistry is the ECR Public (public.ecr.aws/<alias>/<repo>) implementation of Registry
+type ecrPublicRegistry struct {
+	client ecrpublic.DescribeImageTagsAPIClient
+
+	// registryID is only set when not assuming an IAM role, so we query the remote ECR registry directly
+	registryID *string
+
+	// Manifests are fetched anonymously via the public distribution API as ECR Public has no BatchGetImage
+	manifests Registry
+}
+
+func newECRPublicRegistry(clients *awsClientCache, target Target, repoName string) (*ecrPublicRegistry, error) {
+	client, registryID := clients.ecrPublicClient(target, repoName)
+
+	host := ecrPublicHost
+	manifests, err := newOCIRegistry(Target{
+		RegistryHost:      &host,
+		RegistryNamespace: target.RegistryNamespace,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating ECR Public distribution API client: %w", err)
+	}
+
+	return &ecrPublicRegistry{
+		client:     client,
+		registryID: registryID,
+		manifests:  manifests,
+	}, nil
+}
+
+func (r *ecrPublicRegistry) TagExists(ctx context.Context, repoName, tag string) (bool, error) {
+	found, err := r.TagsExist(ctx, repoName, []string{tag})
+	if err != nil {
+		return false, err
+	}
+
+	return found[tag], nil
+}
+
+// TagsExist records whether each tag exists in a single pass through the repo's tags, stopping once all are found
+func (r *ecrPublicRegistry) TagsExist(ctx context.Context, repoName string, tags []string) (map[string]bool, error) {
+	found := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		found[tag] = false
+	}
+	remaining := len(tags)
+
+	err := r.describeImageTags(ctx, repoName, func(imageTag string) bool {
+		if seen, ok := found[imageTag]; ok && !seen {
+			slog.Debug("Found image tag", "registry", ecrPublicHost, "repo", repoName, "tag", imageTag)
+			found[imageTag] = true
+			remaining--
+		}
+		return remaining > 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+func (r *ecrPublicRegistry) ListTags(ctx context.Context, repoName string) ([]string, error) {
+	tags := make([]string, 0)
+
+	err := r.describeImageTags(ctx, repoName, func(imageTag string) bool {
+		tags = append(tags, imageTag)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (r *ecrPublicRegistry) DescribeManifest(ctx context.Context, repoName, tag string) (Manifest, error) {
+	return r.manifests.DescribeManifest(ctx, repoName, tag)
+}
+
+// ConfigPlatform reads the config blob anonymously via the public distribution API, the same as the manifests
+func (r *ecrPublicRegistry) ConfigPlatform(ctx context.Context, repoName, digest string) (string, error) {
+	reader, ok := r.manifests.(configPlatformReader)
+	if !ok {
+		return "", nil
+	}
+	return reader.ConfigPlatform(ctx, repoName, digest)
+}
+
+// describeImageTags pages through the tags in the repo, calling fn for each tag until it returns false
+func (r *ecrPublicRegistry) describeImageTags(ctx context.Context, repoName string, fn func(imageTag string) bool) error {
+	paginator := ecrpublic.NewDescribeImageTagsPaginator(r.client, &ecrpublic.DescribeImageTagsInput{
+		RepositoryName: aws.String(repoName),
+		RegistryId:     r.registryID,
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("describing ECR Public Docker tags for %s: %w", repoName, err)
+		}
+
+		for _, detail := range page.ImageTagDetails {
+			if detail.ImageTag == nil {
+				continue
+			}
+			if !fn(*detail.ImageTag) {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// ecrPublicImageRef returns the full ECR Public image reference
+func ecrPublicImageRef(target Target, repoName, repoTag string) string {
+	return fmt.Sprintf("%s/%s/%s:%s", ecrPublicHost, readStrPointer(target.RegistryNamespace), repoName, repoTag)
+}
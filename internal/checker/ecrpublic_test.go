@@ -0,0 +1,97 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecrpublic"
+	ecrPublicTypes "github.com/aws/aws-sdk-go-v2/service/ecrpublic/types"
+	"github.com/stretchr/testify/require"
+)
+
+type mockECRPublicClient struct {
+	pages *atomic.Int32
+}
+
+func (m mockECRPublicClient) DescribeImageTags(_ context.Context, input *ecrpublic.DescribeImageTagsInput, _ ...func(*ecrpublic.Options)) (*ecrpublic.DescribeImageTagsOutput, error) {
+	if m.pages != nil {
+		m.pages.Add(1)
+	}
+
+	switch *input.RepositoryName {
+	case "repo-1":
+		if input.NextToken != nil && *input.NextToken == "token" {
+			return &ecrpublic.DescribeImageTagsOutput{
+				ImageTagDetails: []ecrPublicTypes.ImageTagDetail{{ImageTag: aws.String("v2")}},
+			}, nil
+		}
+		return &ecrpublic.DescribeImageTagsOutput{
+			ImageTagDetails: []ecrPublicTypes.ImageTagDetail{{ImageTag: aws.String("v1")}, {}},
+			NextToken:       aws.String("token"),
+		}, nil
+	case "error-repo":
+		return nil, errors.New("access denied")
+	}
+
+	return &ecrpublic.DescribeImageTagsOutput{}, nil
+}
+
+func Test_ecrPublicRegistry_TagExists(t *testing.T) {
+	r := &ecrPublicRegistry{client: mockECRPublicClient{}}
+
+	found, err := r.TagExists(context.Background(), "repo-1", "v2")
+	require.NoError(t, err)
+	require.True(t, found, "tag on the second page should be found")
+
+	found, err = r.TagExists(context.Background(), "repo-1", "missing")
+	require.NoError(t, err)
+	require.False(t, found)
+
+	_, err = r.TagExists(context.Background(), "error-repo", "v1")
+	require.Error(t, err)
+}
+
+func Test_ecrPublicRegistry_TagsExist(t *testing.T) {
+	var pages atomic.Int32
+	r := &ecrPublicRegistry{client: mockECRPublicClient{pages: &pages}}
+
+	found, err := r.TagsExist(context.Background(), "repo-1", []string{"v1", "v2", "missing"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"v1": true, "v2": true, "missing": false}, found)
+	require.Equal(t, int32(2), pages.Load(), "the tags should be found in a single pass")
+
+	pages.Store(0)
+	found, err = r.TagsExist(context.Background(), "repo-1", []string{"v1"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"v1": true}, found)
+	require.Equal(t, int32(1), pages.Load(), "paging should stop once every tag is found")
+
+	_, err = r.TagsExist(context.Background(), "error-repo", []string{"v1"})
+	require.Error(t, err)
+}
+
+func Test_ecrPublicRegistry_ListTags(t *testing.T) {
+	r := &ecrPublicRegistry{client: mockECRPublicClient{}}
+
+	tags, err := r.ListTags(context.Background(), "repo-1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1", "v2"}, tags)
+}
+
+func Test_ecrPublicRegistry_DescribeManifest(t *testing.T) {
+	manifests := newFakeRegistry()
+	manifests.addImage("repo-1", "v1", Manifest{Digest: "sha256:123"})
+	r := &ecrPublicRegistry{client: mockECRPublicClient{}, manifests: manifests}
+
+	m, err := r.DescribeManifest(context.Background(), "repo-1", "v1")
+	require.NoError(t, err)
+	require.Equal(t, "sha256:123", m.Digest)
+}
+
+func Test_ecrPublicImageRef(t *testing.T) {
+	ref := ecrPublicImageRef(Target{RegistryNamespace: aws.String("my-alias")}, "repo-1", "v1")
+	require.Equal(t, "public.ecr.aws/my-alias/repo-1:v1", ref)
+}
@@ -0,0 +1,78 @@
+package checker
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// plainTarget has the fields of Target without its UnmarshalYAML method, so a target can be decoded from within it
+type plainTarget Target
+
+// UnmarshalYAML decodes either a target, or the name of an environment of targets defined in config-defaults.yml.
+// The older unmarshaler interface is used as it shares the decoder, so unknown keys in a target are still rejected
+func (t *Target) UnmarshalYAML(unmarshal func(any) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*t = Target{environmentRef: name, isEnvironmentRef: true}
+		return nil
+	}
+
+	return unmarshal((*plainTarget)(t))
+}
+
+// expandEnvironments replaces each reference to an environment with copies of its targets, flagged with the name of
+// the environment. A reference to an undefined environment is kept so that validate can report it
+func expandEnvironments(targets []*Target, environments map[string][]*Target) []*Target {
+	if !slices.ContainsFunc(targets, func(t *Target) bool { return t.isEnvironmentRef }) {
+		return targets
+	}
+
+	expanded := make([]*Target, 0, len(targets))
+	for idx, target := range targets {
+		if !target.isEnvironmentRef {
+			expanded = append(expanded, target)
+			continue
+		}
+
+		envTargets, ok := environments[target.environmentRef]
+		if !ok {
+			ref := *target
+			ref.refIndex = idx
+			expanded = append(expanded, &ref)
+			continue
+		}
+
+		for _, envTarget := range cloneTargets(envTargets) {
+			envTarget.Environment = target.environmentRef
+			expanded = append(expanded, envTarget)
+		}
+	}
+
+	return expanded
+}
+
+// selectEnvironment keeps only the targets of the named environment, skipping the images without any
+func (c *config) selectEnvironment(name string) error {
+	if !c.environmentNames[name] {
+		defined := slices.Sorted(maps.Keys(c.environmentNames))
+		return fmt.Errorf("environment %s is not defined in %s, defined environments: %s", name, defaultConfigFile, strings.Join(defined, ", "))
+	}
+
+	for _, key := range sortedKeys(c.repos) {
+		repo := c.repos[key]
+
+		repo.Targets = slices.DeleteFunc(repo.Targets, func(t *Target) bool { return t.Environment != name })
+		if len(repo.Targets) == 0 {
+			slog.Info("Skipping image as it has no targets in the environment", "path", key, "environment", name)
+			delete(c.repos, key)
+			continue
+		}
+
+		c.repos[key] = repo
+	}
+
+	return nil
+}
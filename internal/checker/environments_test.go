@@ -0,0 +1,108 @@
+package checker
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarget_UnmarshalYAML(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"valid/config.yml":   "targets:\n  - prod\n  - aws_region: eu-west-1\n",
+		"unknown/config.yml": "targets:\n  - prod\n  - aws_regoin: eu-west-1\n",
+	})
+
+	result, _, err := parseYAMLFile(filepath.Join(dir, "valid", "config.yml"), false)
+	require.NoError(t, err)
+	require.Len(t, result.Targets, 2)
+	require.True(t, result.Targets[0].isEnvironmentRef)
+	require.Equal(t, "prod", result.Targets[0].environmentRef)
+	require.False(t, result.Targets[1].isEnvironmentRef)
+	require.Equal(t, "eu-west-1", *result.Targets[1].AwsRegion)
+
+	path := filepath.Join(dir, "unknown", "config.yml")
+	_, _, err = parseYAMLFile(path, false)
+	var problems ValidationErrors
+	require.True(t, errors.As(err, &problems))
+	require.Equal(t, ValidationErrors{
+		{Path: path, Line: 3, Message: "unknown key aws_regoin, did you mean aws_region?"},
+	}, problems, "unknown keys in targets should still be rejected")
+}
+
+func Test_expandEnvironments(t *testing.T) {
+	environments := map[string][]*Target{
+		"prod": {
+			{AwsRegion: aws.String("eu-west-1")},
+			{AwsRegion: aws.String("us-east-1")},
+		},
+	}
+
+	targets := []*Target{
+		{environmentRef: "prod", isEnvironmentRef: true},
+		{AwsRegion: aws.String("ap-northeast-1")},
+		{environmentRef: "missing", isEnvironmentRef: true},
+	}
+
+	result := expandEnvironments(targets, environments)
+	require.Len(t, result, 4)
+	require.Equal(t, "eu-west-1", *result[0].AwsRegion)
+	require.Equal(t, "prod", result[0].Environment)
+	require.Equal(t, "us-east-1", *result[1].AwsRegion)
+	require.NotSame(t, environments["prod"][0], result[0], "environment targets should be copied")
+	require.Equal(t, "ap-northeast-1", *result[2].AwsRegion)
+	require.Empty(t, result[2].Environment)
+	require.True(t, result[3].isEnvironmentRef, "undefined environments should be kept for validate")
+	require.Equal(t, 2, result[3].refIndex)
+}
+
+func Test_loadConfig_environments(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config-defaults.yml": "default_aws_role_name: ecr-query\nrepo_tag: \"1\"\ntarget_platforms: [linux/amd64]\n" +
+			"environments:\n" +
+			"  dev:\n    - aws_account_id: \"111111111111\"\n      aws_region: eu-west-1\n" +
+			"  prod:\n    - aws_account_id: \"222222222222\"\n      aws_region: eu-west-1\n    - aws_account_id: \"222222222222\"\n      aws_region: us-east-1\n",
+		"image-1/Dockerfile": "FROM alpine:3",
+		"image-1/config.yml": "repo_name: image-1\ntargets: [dev, prod]\n",
+		"image-2/Dockerfile": "FROM alpine:3",
+		"image-2/config.yml": "repo_name: image-2\ntargets: [dev]\n",
+	})
+	keyOne := filepath.Join(dir, "image-1", childConfigFile)
+	keyTwo := filepath.Join(dir, "image-2", childConfigFile)
+
+	c, err := loadConfig(Options{ImageDirectory: dir, Concurrency: DefaultConcurrency})
+	require.NoError(t, err)
+	targets := c.repos[keyOne].Targets
+	require.Len(t, targets, 3)
+	require.Equal(t, "dev", targets[0].Environment)
+	require.Equal(t, "arn:aws:iam::111111111111:role/ecr-query", targets[0].AWSRoleARN, "environment targets should be completed by the defaults")
+	require.Equal(t, "prod", targets[2].Environment)
+	require.Equal(t, "222222222222.dkr.ecr.us-east-1.amazonaws.com/image-1:1", targets[2].FullImageRef)
+	require.Nil(t, c.repos[keyOne].Environments)
+
+	c, err = loadConfig(Options{ImageDirectory: dir, Environment: "prod", Concurrency: DefaultConcurrency})
+	require.NoError(t, err)
+	require.Equal(t, []string{keyOne}, sortedKeys(c.repos), "images without targets in the environment should be skipped")
+	require.Len(t, c.repos[keyOne].Targets, 2)
+
+	_, err = loadConfig(Options{ImageDirectory: dir, Environment: "staging", Concurrency: DefaultConcurrency})
+	require.ErrorContains(t, err, "environment staging is not defined in config-defaults.yml, defined environments: dev, prod")
+
+	writeFiles(t, dir, map[string]string{
+		"image-2/config.yml": "repo_name: image-2\ntargets:\n  - dev\n  - staging\n",
+		"image-3/Dockerfile": "FROM alpine:3",
+		"image-3/config.yml": "repo_name: image-3\nenvironments:\n  dev: []\n",
+	})
+
+	_, err = loadConfig(Options{ImageDirectory: dir, Concurrency: DefaultConcurrency})
+	var problems ValidationErrors
+	require.ErrorAs(t, err, &problems)
+	require.Equal(t, ValidationErrors{
+		{Path: keyTwo, Line: 4, Column: 5, Message: "environment staging referenced by target index 1 is not defined in config-defaults.yml"},
+		{Path: filepath.Join(dir, "image-3", childConfigFile), Line: 2, Column: 1, Message: "environments is only supported in config-defaults.yml"},
+	}, problems)
+}
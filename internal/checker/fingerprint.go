@@ -0,0 +1,142 @@
+package checker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+const (
+	// contentHashLength is the number of hex characters of the fingerprint used in the tag
+	contentHashLength = 12
+)
+
+// contentHash fingerprints the build inputs of an image directory: every file in the build context not excluded
+// by .dockerignore, plus each set of build args. Paths are relative and sorted so the result is stable across machines
+func contentHash(dir string, buildArgs ...map[string]string) (string, error) {
+	ignore, err := readDockerignore(dir)
+	if err != nil {
+		return "", err
+	}
+
+	files, err := contextFiles(dir, ignore)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+
+	for _, rel := range files {
+		if err = hashFile(h, dir, rel); err != nil {
+			return "", err
+		}
+	}
+
+	for i, args := range buildArgs {
+		if i > 0 {
+			_, _ = fmt.Fprint(h, "build-args\x00")
+		}
+		for _, k := range slices.Sorted(maps.Keys(args)) {
+			_, _ = fmt.Fprintf(h, "build-arg\x00%s\x00%s\x00", k, args[k])
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// contextFiles returns the sorted slash separated paths of the files in the build context, relative to dir.
+// Files excluded by the .dockerignore file are skipped so that they don't trigger rebuilds
+func contextFiles(dir string, ignore *dockerignore) ([]string, error) {
+	files := make([]string, 0)
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel == "." {
+			return nil
+		}
+
+		// The child config isn't part of the build context. Changes to the build args are hashed separately
+		if rel == childConfigFile {
+			return nil
+		}
+
+		// Docker always sends the Dockerfile and .dockerignore, even if they are excluded
+		if rel == "Dockerfile" || rel == dockerignoreFile {
+			files = append(files, rel)
+			return nil
+		}
+
+		if ignore.ignores(rel) {
+			// Paths within an ignored directory could be re-included by a ! exception
+			if d.IsDir() && !ignore.hasExclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.IsDir() {
+			files = append(files, rel)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking build context %s: %w", dir, err)
+	}
+
+	slices.Sort(files)
+
+	return files, nil
+}
+
+// hashFile writes the path, executable bit and content (or link target) of the file to h
+func hashFile(h io.Writer, dir, rel string) error {
+	p := filepath.Join(dir, filepath.FromSlash(rel))
+
+	info, err := os.Lstat(p)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", p, err)
+	}
+
+	// Only the executable bit is hashed as that is all git preserves between machines
+	executable := info.Mode().Perm()&0o111 != 0
+	_, _ = fmt.Fprintf(h, "file\x00%s\x00%t\x00", rel, executable)
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(p)
+		if err != nil {
+			return fmt.Errorf("reading link %s: %w", p, err)
+		}
+		_, _ = fmt.Fprintf(h, "link\x00%s\x00", target)
+		return nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", p, err)
+	}
+	defer f.Close()
+
+	fileHash := sha256.New()
+	if _, err = io.Copy(fileHash, f); err != nil {
+		return fmt.Errorf("hashing %s: %w", p, err)
+	}
+	_, _ = fmt.Fprintf(h, "%x\x00", fileHash.Sum(nil))
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package checker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles creates the files, keyed by slash separated path, under dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+}
+
+func Test_contentHash(t *testing.T) {
+	files := map[string]string{
+		"Dockerfile":          "FROM alpine:3\nCOPY . /app",
+		"config.yml":          "repo_name: repo-1",
+		"src/main.sh":         "echo hello",
+		"tests/fixture.json":  "{}",
+		"notes.md":            "ignored",
+		dockerignoreFile:      "# comment\n\ntests\n*.md\n",
+		"src/nested/data.txt": "data",
+	}
+	buildArgs := map[string]string{"B": "2", "A": "1"}
+
+	dirOne := t.TempDir()
+	writeFiles(t, dirOne, files)
+	dirTwo := t.TempDir()
+	writeFiles(t, dirTwo, files)
+
+	base, err := contentHash(dirOne, buildArgs)
+	require.NoError(t, err)
+	require.Len(t, base, 64)
+
+	other, err := contentHash(dirTwo, map[string]string{"A": "1", "B": "2"})
+	require.NoError(t, err)
+	require.Equal(t, base, other, "hash must be stable across directories")
+
+	t.Run("Ignored files do not change the hash", func(t *testing.T) {
+		writeFiles(t, dirTwo, map[string]string{"tests/new-fixture.json": "[]", "notes.md": "changed"})
+		result, err := contentHash(dirTwo, buildArgs)
+		require.NoError(t, err)
+		require.Equal(t, base, result)
+	})
+
+	t.Run("Child config does not change the hash", func(t *testing.T) {
+		writeFiles(t, dirTwo, map[string]string{"config.yml": "repo_name: repo-2"})
+		result, err := contentHash(dirTwo, buildArgs)
+		require.NoError(t, err)
+		require.Equal(t, base, result)
+	})
+
+	t.Run("Build args change the hash", func(t *testing.T) {
+		result, err := contentHash(dirOne, map[string]string{"A": "1", "B": "3"})
+		require.NoError(t, err)
+		require.NotEqual(t, base, result)
+	})
+
+	t.Run("Context files change the hash", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, files)
+		writeFiles(t, dir, map[string]string{"src/nested/data.txt": "changed"})
+
+		result, err := contentHash(dir, buildArgs)
+		require.NoError(t, err)
+		require.NotEqual(t, base, result)
+	})
+
+	t.Run("Dockerfile is always hashed", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, files)
+		writeFiles(t, dir, map[string]string{dockerignoreFile: "Dockerfile\n", "Dockerfile": "FROM alpine:3.20"})
+
+		before, err := contentHash(dir, nil)
+		require.NoError(t, err)
+
+		writeFiles(t, dir, map[string]string{"Dockerfile": "FROM alpine:3.21"})
+		after, err := contentHash(dir, nil)
+		require.NoError(t, err)
+		require.NotEqual(t, before, after)
+	})
+
+	t.Run("Executable bit changes the hash", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, files)
+		require.NoError(t, os.Chmod(filepath.Join(dir, "src", "main.sh"), 0o755))
+
+		result, err := contentHash(dir, buildArgs)
+		require.NoError(t, err)
+		require.NotEqual(t, base, result)
+	})
+}
+
+func Test_contentHash_missingDir(t *testing.T) {
+	_, err := contentHash(filepath.Join(t.TempDir(), "missing"), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package checker
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const (
+	ghcrHost            = "ghcr.io"
+	ghcrDefaultTokenEnv = "GITHUB_TOKEN"
+)
+
+// newGHCRRegistry returns an OCI registry client for GitHub Container Registry (ghcr.io/<owner>/<repo>).
+// GHCR accepts any username alongside a token, so the owner is used unless one is explicitly set
+func newGHCRRegistry(target Target) (*ociRegistry, error) {
+	if strPtrEmpty(target.RegistryNamespace) {
+		return nil, fmt.Errorf("registry_namespace must be set to the GitHub owner for registry_type %s", registryTypeGHCR)
+	}
+
+	owner := strings.ToLower(*target.RegistryNamespace)
+	target.RegistryNamespace = &owner
+
+	if strPtrEmpty(target.RegistryHost) {
+		host := ghcrHost
+		target.RegistryHost = &host
+	}
+
+	if strPtrEmpty(target.RegistryUsername) {
+		target.RegistryUsername = &owner
+	}
+
+	// Fall back to the workflow token, or anonymous access for public images if that isn't available either
+	if strPtrEmpty(target.RegistryPasswordEnv) {
+		if os.Getenv(ghcrDefaultTokenEnv) != "" {
+			tokenEnv := ghcrDefaultTokenEnv
+			target.RegistryPasswordEnv = &tokenEnv
+		} else {
+			slog.Warn("No GHCR token available. Using anonymous access", "owner", owner, "env", ghcrDefaultTokenEnv)
+		}
+	}
+
+	return newOCIRegistry(target)
+}
+
+// ghcrImageRef returns the full GHCR image reference. GHCR requires the owner to be lowercase
+func ghcrImageRef(target Target, repoName, repoTag string) string {
+	host := ghcrHost
+	if !strPtrEmpty(target.RegistryHost) {
+		host = *target.RegistryHost
+	}
+
+	return fmt.Sprintf("%s/%s/%s:%s", host, strings.ToLower(readStrPointer(target.RegistryNamespace)), repoName, repoTag)
+}
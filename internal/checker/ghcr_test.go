@@ -0,0 +1,48 @@
+package checker
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newGHCRRegistry(t *testing.T) {
+	t.Setenv(ghcrDefaultTokenEnv, "gh-token")
+
+	r, err := newGHCRRegistry(Target{
+		RegistryType:      aws.String(registryTypeGHCR),
+		RegistryNamespace: aws.String("MichaelPrice232"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "https://ghcr.io", r.baseURL)
+	require.Equal(t, "michaelprice232", r.namespace, "GHCR owner must be lowercase")
+	require.Equal(t, "michaelprice232", r.username)
+	require.Equal(t, "gh-token", r.password, "token should default to GITHUB_TOKEN")
+	require.Equal(t, "michaelprice232/repo-1", r.repoPath("repo-1"))
+
+	t.Setenv("MY_GHCR_TOKEN", "custom-token")
+	r, err = newGHCRRegistry(Target{
+		RegistryType:        aws.String(registryTypeGHCR),
+		RegistryNamespace:   aws.String("my-org"),
+		RegistryPasswordEnv: aws.String("MY_GHCR_TOKEN"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "custom-token", r.password)
+
+	t.Setenv(ghcrDefaultTokenEnv, "")
+	r, err = newGHCRRegistry(Target{
+		RegistryType:      aws.String(registryTypeGHCR),
+		RegistryNamespace: aws.String("my-org"),
+	})
+	require.NoError(t, err)
+	require.Empty(t, r.password, "should fall back to anonymous access")
+
+	_, err = newGHCRRegistry(Target{RegistryType: aws.String(registryTypeGHCR)})
+	require.Error(t, err, "registry_namespace is required")
+}
+
+func Test_ghcrImageRef(t *testing.T) {
+	ref := ghcrImageRef(Target{RegistryNamespace: aws.String("My-Org")}, "repo-1", "v1")
+	require.Equal(t, "ghcr.io/my-org/repo-1:v1", ref)
+}
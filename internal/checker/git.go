@@ -0,0 +1,157 @@
+package checker
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// runGit runs a git command in dir, returning stdout
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+// changedFiles returns the absolute paths of the files changed between the merge base of the since and until refs
+// and until, the same as a pull request diff. Renames are reported as both the old and new path
+func changedFiles(dir, since, until string) ([]string, error) {
+	root, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("finding git repo root: %w", err)
+	}
+	root = strings.TrimSpace(root)
+
+	out, err := runGit(dir, "diff", "--name-only", "--no-renames", "-z", fmt.Sprintf("%s...%s", since, until))
+	if err != nil {
+		return nil, fmt.Errorf("diffing %s...%s: %w", since, until, err)
+	}
+
+	files := make([]string, 0)
+	for _, f := range strings.Split(out, "\x00") {
+		if f == "" {
+			continue
+		}
+		files = append(files, filepath.Join(root, filepath.FromSlash(f)))
+	}
+
+	return files, nil
+}
+
+// imageDirChanged reports whether any of the changed files are in the build context of the image directory.
+// Files excluded by the .dockerignore file are not counted, other than the Dockerfile which Docker always includes
+func imageDirChanged(imageDir string, changed []string, ignore *dockerignore) (bool, error) {
+	absDir, err := filepath.Abs(imageDir)
+	if err != nil {
+		return false, fmt.Errorf("resolving path %s: %w", imageDir, err)
+	}
+
+	// git reports paths from the real repo root, so symlinks such as a temp directory must be resolved
+	absDir, err = filepath.EvalSymlinks(absDir)
+	if err != nil {
+		return false, fmt.Errorf("resolving symlinks in %s: %w", imageDir, err)
+	}
+
+	for _, f := range changed {
+		rel, err := filepath.Rel(absDir, f)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel == "Dockerfile" || rel == dockerignoreFile || rel == childConfigFile || !ignore.ignores(rel) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// defaultsChanged reports whether any of the defaults files applying to the image directory have changed: the root
+// defaults file, or a nested defaults file in the base image directory or between it and the image directory
+func (c *config) defaultsChanged(imageDirectory, imageDir string, changed []string) (bool, error) {
+	rel, err := filepath.Rel(imageDirectory, imageDir)
+	if err != nil {
+		return false, fmt.Errorf("resolving %s relative to %s: %w", imageDir, imageDirectory, err)
+	}
+
+	defaultsFiles := make([]string, 0)
+	if c.rootDefaultsFile != "" {
+		defaultsFiles = append(defaultsFiles, c.rootDefaultsFile)
+	}
+	for _, dir := range ancestorDirs(imageDirectory, filepath.ToSlash(rel)) {
+		defaultsFiles = append(defaultsFiles, filepath.Join(dir, defaultConfigFile))
+	}
+
+	for _, f := range defaultsFiles {
+		// The file may have been deleted, so only its directory is resolved the same as imageDirChanged
+		dir, err := filepath.Abs(filepath.Dir(f))
+		if err != nil {
+			return false, fmt.Errorf("resolving path %s: %w", f, err)
+		}
+		if dir, err = filepath.EvalSymlinks(dir); err != nil {
+			return false, fmt.Errorf("resolving symlinks in %s: %w", dir, err)
+		}
+
+		if slices.Contains(changed, filepath.Join(dir, filepath.Base(f))) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// filterChangedRepos removes the repos whose image directory and defaults files have not changed between the git refs
+func (c *config) filterChangedRepos(imageDirectory, since, until string) error {
+	changed, err := changedFiles(imageDirectory, since, until)
+	if err != nil {
+		return err
+	}
+	slog.Debug("Changed files", "since", since, "until", until, "count", len(changed))
+
+	for _, key := range sortedKeys(c.repos) {
+		imageDir := path.Dir(key)
+
+		ignore, err := readDockerignore(imageDir)
+		if err != nil {
+			return err
+		}
+
+		dirChanged, err := imageDirChanged(imageDir, changed, ignore)
+		if err != nil {
+			return err
+		}
+		if dirChanged {
+			continue
+		}
+
+		// Defaults can change the image's build args, tags or registries without touching its directory
+		defaultsChanged, err := c.defaultsChanged(imageDirectory, imageDir, changed)
+		if err != nil {
+			return err
+		}
+		if defaultsChanged {
+			slog.Info("Including image as its defaults have changed", "path", key, "since", since)
+			continue
+		}
+
+		slog.Info("Skipping image as directory is unchanged", "path", key, "since", since)
+		delete(c.repos, key)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package checker
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestGitRepo creates a git repo containing the files in an initial commit, returning its path
+func newTestGitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+
+	_, err := runGit(dir, "init", "--quiet", "--initial-branch=main")
+	require.NoError(t, err)
+	commitAll(t, dir, "initial")
+
+	return dir
+}
+
+func commitAll(t *testing.T, dir, message string) {
+	t.Helper()
+
+	_, err := runGit(dir, "add", "-A")
+	require.NoError(t, err)
+	_, err = runGit(dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--allow-empty", "-m", message)
+	require.NoError(t, err)
+}
+
+func Test_filterChangedRepos(t *testing.T) {
+	dir := newTestGitRepo(t, map[string]string{
+		"images/image-1/Dockerfile":          "FROM alpine:3",
+		"images/image-1/config.yml":          "repo_name: image-1",
+		"images/image-2/Dockerfile":          "FROM alpine:3",
+		"images/image-2/config.yml":          "repo_name: image-2",
+		"images/image-2/.dockerignore":       "tests",
+		"images/image-2/tests/fixture.json":  "{}",
+		"images/image-3/Dockerfile":          "FROM alpine:3",
+		"images/image-3/config.yml":          "repo_name: image-3",
+		"images/image-10/Dockerfile":         "FROM alpine:3",
+		"images/image-10/config.yml":         "repo_name: image-10",
+		"images/image-1-unrelated/readme.md": "unrelated",
+	})
+
+	_, err := runGit(dir, "branch", "base")
+	require.NoError(t, err)
+
+	writeFiles(t, dir, map[string]string{
+		"images/image-1/Dockerfile":          "FROM alpine:3.20",
+		"images/image-2/tests/fixture.json":  "[]",
+		"images/image-1-unrelated/readme.md": "changed",
+		"images/image-10/config.yml":         "repo_name: image-10\nrepo_tag: v2",
+	})
+	commitAll(t, dir, "change images")
+
+	imageDir := filepath.Join(dir, "images")
+	keys := make([]string, 0)
+	repos := make(map[string]repoConfig)
+	for _, name := range []string{"image-1", "image-2", "image-3", "image-10"} {
+		key := filepath.Join(imageDir, name, childConfigFile)
+		keys = append(keys, key)
+		repos[key] = repoConfig{RepoName: aws.String(name)}
+	}
+
+	c := config{repos: repos}
+	err = c.filterChangedRepos(imageDir, "base", "HEAD")
+	require.NoError(t, err)
+
+	require.Contains(t, c.repos, keys[0], "Dockerfile change")
+	require.NotContains(t, c.repos, keys[1], "only .dockerignore excluded files changed")
+	require.NotContains(t, c.repos, keys[2], "unchanged")
+	require.Contains(t, c.repos, keys[3], "config.yml change")
+
+	err = c.filterChangedRepos(imageDir, "missing-ref", "HEAD")
+	require.Error(t, err)
+}
+
+func Test_filterChangedRepos_defaults(t *testing.T) {
+	dir := newTestGitRepo(t, map[string]string{
+		"config-defaults.yml":               "default_aws_region: eu-west-2",
+		"images/team-a/config-defaults.yml": "build_args:\n  BASE: alpine:3",
+		"images/team-a/a/Dockerfile":        "FROM alpine:3",
+		"images/team-a/a/config.yml":        "repo_name: a",
+		"images/team-b/b/Dockerfile":        "FROM alpine:3",
+		"images/team-b/b/config.yml":        "repo_name: b",
+	})
+
+	_, err := runGit(dir, "branch", "base")
+	require.NoError(t, err)
+
+	imageDir := filepath.Join(dir, "images")
+	keyA := filepath.Join(imageDir, "team-a", "a", childConfigFile)
+	keyB := filepath.Join(imageDir, "team-b", "b", childConfigFile)
+	newConfig := func() config {
+		return config{
+			repos:            map[string]repoConfig{keyA: {RepoName: aws.String("a")}, keyB: {RepoName: aws.String("b")}},
+			rootDefaultsFile: filepath.Join(dir, defaultConfigFile),
+		}
+	}
+
+	writeFiles(t, dir, map[string]string{"images/team-a/config-defaults.yml": "build_args:\n  BASE: alpine:3.20"})
+	commitAll(t, dir, "change nested defaults")
+
+	c := newConfig()
+	require.NoError(t, c.filterChangedRepos(imageDir, "base", "HEAD"))
+	require.Contains(t, c.repos, keyA, "nested defaults of the image changed")
+	require.NotContains(t, c.repos, keyB, "nested defaults of another directory changed")
+
+	writeFiles(t, dir, map[string]string{"config-defaults.yml": "default_aws_region: us-east-1"})
+	commitAll(t, dir, "change root defaults")
+
+	c = newConfig()
+	require.NoError(t, c.filterChangedRepos(imageDir, "HEAD~1", "HEAD"))
+	require.Contains(t, c.repos, keyA, "root defaults changed")
+	require.Contains(t, c.repos, keyB, "root defaults changed")
+}
+
+func Test_changedFiles(t *testing.T) {
+	dir := newTestGitRepo(t, map[string]string{"a/file.txt": "a", "b/file.txt": "b"})
+
+	_, err := runGit(dir, "branch", "base")
+	require.NoError(t, err)
+
+	_, err = runGit(dir, "mv", "a/file.txt", "a/renamed.txt")
+	require.NoError(t, err)
+	commitAll(t, dir, "rename")
+
+	files, err := changedFiles(filepath.Join(dir, "b"), "base", "HEAD")
+	require.NoError(t, err)
+
+	root, err := filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{filepath.Join(root, "a", "file.txt"), filepath.Join(root, "a", "renamed.txt")}, files,
+		"renames should report both paths, relative to the repo root rather than the directory")
+}
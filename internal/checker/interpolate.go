@@ -0,0 +1,92 @@
+package checker
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// envReference matches ${VAR} and ${VAR:-default}, an unclosed ${ so that it can be reported, and the $${ escape
+	// for a literal ${
+	envReference = regexp.MustCompile(`\$\$\{|\$\{[^}]*\}?`)
+
+	envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// interpolateEnv expands environment variable references in the values of the YAML document in place, so they apply
+// to every string field. Keys aren't expanded. Each reference which can't be expanded is returned as a problem
+func interpolateEnv(path string, node *yaml.Node) ValidationErrors {
+	problems := make(ValidationErrors, 0)
+
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			problems = append(problems, interpolateEnv(path, child)...)
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			problems = append(problems, interpolateEnv(path, node.Content[i])...)
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "${") {
+			return problems
+		}
+
+		value, err := expandEnv(node.Value)
+		if err != nil {
+			return append(problems, &ValidationError{Path: path, Line: node.Line, Column: node.Column, Message: err.Error()})
+		}
+
+		// Unquoted values are resolved again, so that a variable can hold a bool such as registry_insecure
+		if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			node.Tag = ""
+		}
+		node.Value = value
+	}
+
+	return problems
+}
+
+// expandEnv replaces ${VAR} with the value of the environment variable, which must be set, and ${VAR:-default} with
+// the value of the variable or the default if it's unset or empty
+func expandEnv(value string) (string, error) {
+	var expandErr error
+
+	expanded := envReference.ReplaceAllStringFunc(value, func(ref string) string {
+		if ref == "$${" {
+			return "${"
+		}
+
+		if expandErr != nil {
+			return ref
+		}
+
+		if !strings.HasSuffix(ref, "}") {
+			expandErr = fmt.Errorf("unclosed variable reference %s", ref)
+			return ref
+		}
+
+		name, fallback, hasFallback := strings.Cut(ref[2:len(ref)-1], ":-")
+		if !envVarName.MatchString(name) {
+			expandErr = fmt.Errorf("invalid variable reference %s", ref)
+			return ref
+		}
+
+		v, ok := os.LookupEnv(name)
+		if hasFallback && v == "" {
+			return fallback
+		}
+		if !ok {
+			expandErr = fmt.Errorf("environment variable %s is not set, referenced by %s", name, ref)
+			return ref
+		}
+
+		return v
+	})
+
+	return expanded, expandErr
+}
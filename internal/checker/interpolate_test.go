@@ -0,0 +1,82 @@
+package checker
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_expandEnv(t *testing.T) {
+	t.Setenv("INTERPOLATE_ACCOUNT_ID", "633681147894")
+	t.Setenv("INTERPOLATE_EMPTY", "")
+
+	cases := []struct {
+		testName    string
+		value       string
+		expected    string
+		expectError bool
+	}{
+		{testName: "Variable", value: "${INTERPOLATE_ACCOUNT_ID}", expected: "633681147894"},
+		{testName: "Within a value", value: "arn:${INTERPOLATE_ACCOUNT_ID}:role", expected: "arn:633681147894:role"},
+		{testName: "Default not used", value: "${INTERPOLATE_ACCOUNT_ID:-111111111111}", expected: "633681147894"},
+		{testName: "Default of unset variable", value: "${INTERPOLATE_UNSET:-eu-west-1}", expected: "eu-west-1"},
+		{testName: "Default of empty variable", value: "${INTERPOLATE_EMPTY:-eu-west-1}", expected: "eu-west-1"},
+		{testName: "Empty default", value: "${INTERPOLATE_UNSET:-}", expected: ""},
+		{testName: "Empty variable", value: "${INTERPOLATE_EMPTY}", expected: ""},
+		{testName: "Escaped", value: "$${INTERPOLATE_ACCOUNT_ID}", expected: "${INTERPOLATE_ACCOUNT_ID}"},
+		{testName: "No references", value: "$HOME and $", expected: "$HOME and $"},
+		{testName: "Unset variable", value: "${INTERPOLATE_UNSET}", expectError: true},
+		{testName: "Unclosed reference", value: "${INTERPOLATE_ACCOUNT_ID", expectError: true},
+		{testName: "Invalid name", value: "${1INVALID}", expectError: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := expandEnv(tc.value)
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func Test_parseYAMLFile_interpolation(t *testing.T) {
+	t.Setenv("INTERPOLATE_ACCOUNT_ID", "012345678901")
+	t.Setenv("INTERPOLATE_INSECURE", "true")
+	t.Setenv("INTERPOLATE_MIRROR", "mirror.example.com")
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"valid/config.yml": "default_aws_account_id: ${INTERPOLATE_ACCOUNT_ID}\n" +
+			"repo_name: \"${INTERPOLATE_REPO:-image-1}\"\n" +
+			"build_args:\n  MIRROR: ${INTERPOLATE_MIRROR}\n" +
+			"targets:\n  - registry_type: oci\n    registry_host: harbor.example.com\n    registry_insecure: ${INTERPOLATE_INSECURE}\n",
+		"invalid/config.yml": "repo_name: ${INTERPOLATE_UNSET}\nrepo_tagg: \"1\"\ntargets:\n  - aws_region: ${INTERPOLATE_UNSET:-eu-west-1}\n    aws_role_name: ${INTERPOLATE_ROLE\n",
+	})
+
+	result, _, err := parseYAMLFile(filepath.Join(dir, "valid", "config.yml"), false)
+	require.NoError(t, err)
+	require.Equal(t, "012345678901", *result.DefaultAwsAccountId, "account IDs should keep their leading zeros")
+	require.Equal(t, "image-1", *result.RepoName)
+	require.Equal(t, map[string]string{"MIRROR": "mirror.example.com"}, result.BuildArgs)
+	require.True(t, *result.Targets[0].RegistryInsecure, "unquoted values should be resolved after expanding")
+
+	path := filepath.Join(dir, "invalid", "config.yml")
+	_, _, err = parseYAMLFile(path, false)
+
+	var problems ValidationErrors
+	require.True(t, errors.As(err, &problems))
+	require.Equal(t, ValidationErrors{
+		{Path: path, Line: 1, Column: 12, Message: "environment variable INTERPOLATE_UNSET is not set, referenced by ${INTERPOLATE_UNSET}"},
+		{Path: path, Line: 2, Message: "unknown key repo_tagg, did you mean repo_tag?"},
+		{Path: path, Line: 5, Column: 20, Message: "unclosed variable reference ${INTERPOLATE_ROLE"},
+	}, problems)
+}
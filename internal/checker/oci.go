@@ -0,0 +1,369 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ociRequestTimeout = 30 * time.Second
+
+// ociRegistry is an implementation of Registry for any registry supporting the OCI distribution spec (registry v2 HTTP API)
+type ociRegistry struct {
+	baseURL    string
+	namespace  string
+	username   string
+	password   string
+	httpClient *http.Client
+
+	// Bearer tokens are cached per scope so that we only authenticate once per repo
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+func newOCIRegistry(target Target) (*ociRegistry, error) {
+	if strPtrEmpty(target.RegistryHost) {
+		return nil, fmt.Errorf("registry_host must be set for registry_type %s", registryTypeOCI)
+	}
+
+	scheme := "https"
+	if target.RegistryInsecure != nil && *target.RegistryInsecure {
+		scheme = "http"
+	}
+
+	r := &ociRegistry{
+		baseURL:    fmt.Sprintf("%s://%s", scheme, *target.RegistryHost),
+		namespace:  readStrPointer(target.RegistryNamespace),
+		username:   readStrPointer(target.RegistryUsername),
+		httpClient: &http.Client{Timeout: ociRequestTimeout},
+		tokens:     make(map[string]string),
+	}
+
+	if !strPtrEmpty(target.RegistryPasswordEnv) {
+		r.password = os.Getenv(*target.RegistryPasswordEnv)
+		if r.password == "" {
+			return nil, fmt.Errorf("environment variable %s referenced by registry_password_env is empty", *target.RegistryPasswordEnv)
+		}
+	}
+
+	return r, nil
+}
+
+func (r *ociRegistry) TagExists(ctx context.Context, repoName, tag string) (bool, error) {
+	repoName = r.repoPath(repoName)
+
+	resp, err := r.do(ctx, http.MethodHead, repoName, fmt.Sprintf("/v2/%s/manifests/%s", repoName, tag))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		slog.Debug("Found image tag", "registry", r.baseURL, "repo", repoName, "tag", tag)
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("checking manifest %s:%s: unexpected status %s", repoName, tag, resp.Status)
+	}
+}
+
+func (r *ociRegistry) ListTags(ctx context.Context, repoName string) ([]string, error) {
+	repoName = r.repoPath(repoName)
+	tags := make([]string, 0)
+	next := fmt.Sprintf("/v2/%s/tags/list", repoName)
+
+	for next != "" {
+		resp, err := r.do(ctx, http.MethodGet, repoName, next)
+		if err != nil {
+			return nil, err
+		}
+
+		// The repo not existing yet means there are no tags
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			return tags, nil
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("listing tags for %s: unexpected status %s", repoName, resp.Status)
+		}
+
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding tags list for %s: %w", repoName, err)
+		}
+		tags = append(tags, page.Tags...)
+
+		next = nextLink(resp.Header.Get("Link"))
+	}
+
+	return tags, nil
+}
+
+func (r *ociRegistry) DescribeManifest(ctx context.Context, repoName, tag string) (Manifest, error) {
+	repoName = r.repoPath(repoName)
+
+	resp, err := r.do(ctx, http.MethodGet, repoName, fmt.Sprintf("/v2/%s/manifests/%s", repoName, tag))
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Manifest{}, fmt.Errorf("%s:%s: %w", repoName, tag, ErrManifestNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Manifest{}, fmt.Errorf("getting manifest %s:%s: unexpected status %s", repoName, tag, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("reading manifest %s:%s: %w", repoName, tag, err)
+	}
+
+	return parseManifest(resp.Header.Get("Content-Type"), resp.Header.Get("Docker-Content-Digest"), body)
+}
+
+// ConfigPlatform returns the platform of an image from its config blob
+func (r *ociRegistry) ConfigPlatform(ctx context.Context, repoName, digest string) (string, error) {
+	repoName = r.repoPath(repoName)
+
+	resp, err := r.do(ctx, http.MethodGet, repoName, fmt.Sprintf("/v2/%s/blobs/%s", repoName, digest))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("getting blob %s@%s: unexpected status %s", repoName, digest, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading blob %s@%s: %w", repoName, digest, err)
+	}
+
+	return parseConfigPlatform(body)
+}
+
+// repoPath prefixes the repo name with the namespace if one is configured e.g. <owner>/<repo>
+func (r *ociRegistry) repoPath(repoName string) string {
+	if r.namespace == "" {
+		return repoName
+	}
+	return r.namespace + "/" + repoName
+}
+
+// do sends the request, authenticating and retrying once if the registry responds with an auth challenge
+func (r *ociRegistry) do(ctx context.Context, method, repoName, path string) (*http.Response, error) {
+	scope := fmt.Sprintf("repository:%s:pull", repoName)
+
+	r.mu.Lock()
+	token := r.tokens[scope]
+	r.mu.Unlock()
+
+	resp, err := r.send(ctx, method, path, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+
+	switch {
+	case strings.HasPrefix(strings.ToLower(challenge), "bearer "):
+	case strings.HasPrefix(strings.ToLower(challenge), "basic "):
+		// Basic credentials, if any, were already sent with the first attempt so retrying can't succeed
+		return nil, fmt.Errorf("not authorized to access %s in %s: %s", repoName, r.baseURL, resp.Status)
+	default:
+		return nil, fmt.Errorf("unsupported auth challenge from %s: %q", r.baseURL, challenge)
+	}
+
+	token, err = r.fetchToken(ctx, challenge, scope)
+	if err != nil {
+		return nil, fmt.Errorf("fetching bearer token for %s: %w", repoName, err)
+	}
+
+	r.mu.Lock()
+	r.tokens[scope] = token
+	r.mu.Unlock()
+
+	resp, err = r.send(ctx, method, path, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		resp.Body.Close()
+		return nil, fmt.Errorf("not authorized to access %s in %s: %s", repoName, r.baseURL, resp.Status)
+	}
+
+	return resp, nil
+}
+
+// send performs a single request. Basic auth is used if no bearer token is passed and credentials are available.
+// Credentials are only attached when the request is to the registry itself, not an absolute Link to another host
+func (r *ociRegistry) send(ctx context.Context, method, path, token string) (*http.Response, error) {
+	target := path
+	sameHost := true
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		sameHost = r.isRegistryURL(path)
+	} else {
+		target = r.baseURL + path
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", strings.Join(acceptedManifestMediaTypes, ", "))
+
+	switch {
+	case !sameHost:
+		slog.Debug("Not sending credentials to another host", "registry", r.baseURL, "url", target)
+	case token != "":
+		req.Header.Set("Authorization", "Bearer "+token)
+	case r.password != "":
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, target, err)
+	}
+
+	return resp, nil
+}
+
+// isRegistryURL reports whether the absolute URL has the same scheme and host as the registry
+func (r *ociRegistry) isRegistryURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	base, err := url.Parse(r.baseURL)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Scheme, base.Scheme) && strings.EqualFold(u.Host, base.Host)
+}
+
+// fetchToken requests a bearer token from the realm advertised in the WWW-Authenticate challenge
+func (r *ociRegistry) fetchToken(ctx context.Context, challenge, scope string) (string, error) {
+	params := parseAuthChallenge(challenge)
+
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("no realm in auth challenge %q", challenge)
+	}
+
+	u, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("parsing realm %s: %w", realm, err)
+	}
+
+	q := u.Query()
+	if service := params["service"]; service != "" {
+		q.Set("service", service)
+	}
+	if params["scope"] != "" {
+		scope = params["scope"]
+	}
+	q.Set("scope", scope)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("creating token request: %w", err)
+	}
+	if r.password != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("requesting token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("requesting token from %s: unexpected status %s", realm, resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decoding token response: %w", err)
+	}
+
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+
+	return "", fmt.Errorf("no token returned from %s", realm)
+}
+
+// parseAuthChallenge parses the key/value pairs from a WWW-Authenticate header e.g. Bearer realm="x",service="y"
+func parseAuthChallenge(challenge string) map[string]string {
+	params := make(map[string]string)
+
+	_, rest, found := strings.Cut(challenge, " ")
+	if !found {
+		return params
+	}
+
+	for rest != "" {
+		var key, value string
+		key, rest, found = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if !found {
+			break
+		}
+
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+
+		params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+
+	return params
+}
+
+// nextLink returns the target of a rel="next" Link header, used for paginating the tags list
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, found := strings.Cut(link, ";")
+		if !found || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		return strings.Trim(strings.TrimSpace(target), "<>")
+	}
+
+	return ""
+}
@@ -0,0 +1,228 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRegistryUsername = "user"
+	testRegistryPassword = "secret"
+	testRegistryToken    = "valid-token"
+)
+
+// newTestOCIServer returns a minimal distribution spec registry which requires bearer token auth.
+// The repo "repo-1" contains the tags v1 (an OCI index) and v2, with the tags list paginated one per page
+func newTestOCIServer(t *testing.T, tokenRequests *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	index := `{"mediaType": "application/vnd.oci.image.index.v1+json", "manifests": [{"platform": {"os": "linux", "architecture": "amd64"}}, {"platform": {"os": "linux", "architecture": "arm64"}}]}`
+
+	mux := http.NewServeMux()
+	var server *httptest.Server
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests.Add(1)
+
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != testRegistryUsername || pass != testRegistryPassword {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("service") != "test-registry" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": testRegistryToken})
+	})
+
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testRegistryToken {
+			scope := "repository:" + strings.Split(strings.TrimPrefix(r.URL.Path, "/v2/"), "/")[0] + ":pull"
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test-registry",scope="%s"`, server.URL, scope))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/repo-1/manifests/v1":
+			w.Header().Set("Content-Type", mediaTypeOCIIndex)
+			w.Header().Set("Docker-Content-Digest", "sha256:123")
+			_, _ = w.Write([]byte(index))
+		case "/v2/repo-1/manifests/v2":
+			w.Header().Set("Content-Type", mediaTypeOCIManifest)
+			_, _ = w.Write([]byte(`{"schemaVersion": 2}`))
+		case "/v2/repo-1/blobs/sha256:config":
+			_, _ = w.Write([]byte(`{"architecture": "amd64", "os": "linux", "rootfs": {}}`))
+		case "/v2/repo-1/tags/list":
+			if r.URL.Query().Get("last") == "" {
+				w.Header().Set("Link", `</v2/repo-1/tags/list?n=1&last=v1>; rel="next"`)
+				_ = json.NewEncoder(w).Encode(map[string]any{"name": "repo-1", "tags": []string{"v1"}})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"name": "repo-1", "tags": []string{"v2"}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newTestOCIRegistry(t *testing.T, server *httptest.Server) *ociRegistry {
+	t.Helper()
+	t.Setenv("TEST_REGISTRY_PASSWORD", testRegistryPassword)
+
+	r, err := newOCIRegistry(Target{
+		RegistryType:        aws.String(registryTypeOCI),
+		RegistryHost:        aws.String(strings.TrimPrefix(server.URL, "http://")),
+		RegistryUsername:    aws.String(testRegistryUsername),
+		RegistryPasswordEnv: aws.String("TEST_REGISTRY_PASSWORD"),
+		RegistryInsecure:    aws.Bool(true),
+	})
+	require.NoError(t, err)
+
+	return r
+}
+
+func Test_ociRegistry_TagExists(t *testing.T) {
+	var tokenRequests atomic.Int32
+	r := newTestOCIRegistry(t, newTestOCIServer(t, &tokenRequests))
+
+	found, err := r.TagExists(context.Background(), "repo-1", "v1")
+	require.NoError(t, err)
+	require.True(t, found)
+
+	found, err = r.TagExists(context.Background(), "repo-1", "missing")
+	require.NoError(t, err)
+	require.False(t, found)
+
+	require.Equal(t, int32(1), tokenRequests.Load(), "the bearer token should be cached per repo")
+}
+
+func Test_ociRegistry_ListTags(t *testing.T) {
+	var tokenRequests atomic.Int32
+	r := newTestOCIRegistry(t, newTestOCIServer(t, &tokenRequests))
+
+	tags, err := r.ListTags(context.Background(), "repo-1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1", "v2"}, tags)
+
+	tags, err = r.ListTags(context.Background(), "missing-repo")
+	require.NoError(t, err)
+	require.Empty(t, tags)
+}
+
+func Test_ociRegistry_DescribeManifest(t *testing.T) {
+	var tokenRequests atomic.Int32
+	r := newTestOCIRegistry(t, newTestOCIServer(t, &tokenRequests))
+
+	m, err := r.DescribeManifest(context.Background(), "repo-1", "v1")
+	require.NoError(t, err)
+	require.Equal(t, "sha256:123", m.Digest)
+	require.Equal(t, mediaTypeOCIIndex, m.MediaType)
+	require.Equal(t, []string{"linux/amd64", "linux/arm64"}, m.Platforms)
+
+	_, err = r.DescribeManifest(context.Background(), "repo-1", "missing")
+	require.ErrorIs(t, err, ErrManifestNotFound)
+}
+
+func Test_ociRegistry_ConfigPlatform(t *testing.T) {
+	var tokenRequests atomic.Int32
+	r := newTestOCIRegistry(t, newTestOCIServer(t, &tokenRequests))
+
+	platform, err := r.ConfigPlatform(context.Background(), "repo-1", "sha256:config")
+	require.NoError(t, err)
+	require.Equal(t, "linux/amd64", platform)
+
+	_, err = r.ConfigPlatform(context.Background(), "repo-1", "sha256:missing")
+	require.Error(t, err)
+}
+
+func Test_ociRegistry_badCredentials(t *testing.T) {
+	var tokenRequests atomic.Int32
+	r := newTestOCIRegistry(t, newTestOCIServer(t, &tokenRequests))
+	r.password = "wrong"
+
+	_, err := r.TagExists(context.Background(), "repo-1", "v1")
+	require.Error(t, err)
+}
+
+func Test_ociRegistry_basicChallenge(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("WWW-Authenticate", `Basic realm="test-registry"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+	r := newTestOCIRegistry(t, server)
+
+	_, err := r.TagExists(context.Background(), "repo-1", "v1")
+	require.Error(t, err)
+	require.Equal(t, int32(1), requests.Load(), "basic credentials are sent up front so the request isn't retried")
+}
+
+func Test_ociRegistry_crossHostLink(t *testing.T) {
+	var leaked atomic.Bool
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			leaked.Store(true)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"name": "repo-1", "tags": []string{"v2"}})
+	}))
+	t.Cleanup(other.Close)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", fmt.Sprintf(`<%s/v2/repo-1/tags/list?last=v1>; rel="next"`, other.URL))
+		_ = json.NewEncoder(w).Encode(map[string]any{"name": "repo-1", "tags": []string{"v1"}})
+	}))
+	t.Cleanup(server.Close)
+	r := newTestOCIRegistry(t, server)
+
+	tags, err := r.ListTags(context.Background(), "repo-1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1", "v2"}, tags)
+	require.False(t, leaked.Load(), "credentials should not be sent to another host")
+}
+
+func Test_newOCIRegistry(t *testing.T) {
+	_, err := newOCIRegistry(Target{RegistryType: aws.String(registryTypeOCI)})
+	require.Error(t, err, "registry_host is required")
+
+	_, err = newOCIRegistry(Target{
+		RegistryHost:        aws.String("localhost:5000"),
+		RegistryPasswordEnv: aws.String("ECR_IMAGE_CHECKER_UNSET_VAR"),
+	})
+	require.Error(t, err, "referenced password env var must be set")
+
+	r, err := newOCIRegistry(Target{RegistryHost: aws.String("harbor.example.com")})
+	require.NoError(t, err)
+	require.Equal(t, "https://harbor.example.com", r.baseURL)
+}
+
+func Test_parseAuthChallenge(t *testing.T) {
+	params := parseAuthChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:repo-1:pull,push"`)
+	require.Equal(t, "https://auth.example.com/token", params["realm"])
+	require.Equal(t, "registry.example.com", params["service"])
+	require.Equal(t, "repository:repo-1:pull,push", params["scope"])
+
+	require.Empty(t, parseAuthChallenge("Bearer"))
+}
+
+func Test_nextLink(t *testing.T) {
+	require.Equal(t, "/v2/repo/tags/list?last=b&n=2", nextLink(`</v2/repo/tags/list?last=b&n=2>; rel="next"`))
+	require.Equal(t, "", nextLink(""))
+}
@@ -0,0 +1,148 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+// acceptedManifestMediaTypes is the list of manifest formats requested from the registries
+var acceptedManifestMediaTypes = []string{
+	mediaTypeOCIIndex,
+	mediaTypeDockerManifestList,
+	mediaTypeOCIManifest,
+	mediaTypeDockerManifest,
+}
+
+// ErrManifestNotFound is returned by Registry.DescribeManifest when the tag does not exist
+var ErrManifestNotFound = errors.New("manifest not found")
+
+// Registry is a container registry which the image tags can be checked against
+type Registry interface {
+	// TagExists reports whether the tag is present in the repo
+	TagExists(ctx context.Context, repoName, tag string) (bool, error)
+
+	// ListTags returns all the tags present in the repo
+	ListTags(ctx context.Context, repoName string) ([]string, error)
+
+	// DescribeManifest returns the manifest referenced by the tag. ErrManifestNotFound is returned if it is missing
+	DescribeManifest(ctx context.Context, repoName, tag string) (Manifest, error)
+}
+
+// batchTagChecker is implemented by registries which can check multiple tags in a single call
+type batchTagChecker interface {
+	TagsExist(ctx context.Context, repoName string, tags []string) (map[string]bool, error)
+}
+
+// configPlatformReader is implemented by registries which can read the platform of a single image manifest from
+// its config blob
+type configPlatformReader interface {
+	ConfigPlatform(ctx context.Context, repoName, digest string) (string, error)
+}
+
+// tagsExist reports whether each tag is present in the repo, batching the lookups if the registry supports it
+func tagsExist(ctx context.Context, registry Registry, repoName string, tags []string) (map[string]bool, error) {
+	if batch, ok := registry.(batchTagChecker); ok {
+		return batch.TagsExist(ctx, repoName, tags)
+	}
+
+	found := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		exists, err := registry.TagExists(ctx, repoName, tag)
+		if err != nil {
+			return nil, err
+		}
+		found[tag] = exists
+	}
+
+	return found, nil
+}
+
+// Manifest is a registry agnostic view of an image manifest or manifest list/index
+type Manifest struct {
+	MediaType string
+	Digest    string
+
+	// Platforms is only populated for multi-arch manifest lists and OCI indexes, in the os/arch[/variant] form
+	Platforms []string
+
+	// ConfigDigest is the digest of the image config blob, only set for single image manifests
+	ConfigDigest string
+}
+
+type rawPlatform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant"`
+}
+
+// String returns the platform in the os/arch[/variant] form
+func (p rawPlatform) String() string {
+	platform := fmt.Sprintf("%s/%s", p.OS, p.Architecture)
+	if p.Variant != "" {
+		platform += "/" + p.Variant
+	}
+	return platform
+}
+
+type rawManifest struct {
+	MediaType string `json:"mediaType"`
+	Config    *struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Manifests []struct {
+		Platform *rawPlatform `json:"platform"`
+	} `json:"manifests"`
+}
+
+// parseManifest converts a raw manifest body into a Manifest. mediaType is used if the body does not declare one
+func parseManifest(mediaType, digest string, body []byte) (Manifest, error) {
+	var raw rawManifest
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return Manifest{}, fmt.Errorf("unmarshalling manifest: %w", err)
+	}
+
+	m := Manifest{
+		MediaType: raw.MediaType,
+		Digest:    digest,
+	}
+	if m.MediaType == "" {
+		m.MediaType = mediaType
+	}
+	if raw.Config != nil {
+		m.ConfigDigest = raw.Config.Digest
+	}
+
+	for _, entry := range raw.Manifests {
+		// Attestation manifests are stored with an unknown/unknown platform by buildx
+		if entry.Platform == nil || entry.Platform.OS == "unknown" {
+			continue
+		}
+
+		m.Platforms = append(m.Platforms, entry.Platform.String())
+	}
+
+	return m, nil
+}
+
+// parseConfigPlatform returns the platform of an image from its config blob, in the os/arch[/variant] form
+func parseConfigPlatform(body []byte) (string, error) {
+	var config rawPlatform
+	if err := json.Unmarshal(body, &config); err != nil {
+		return "", fmt.Errorf("unmarshalling image config: %w", err)
+	}
+
+	if config.OS == "" || config.Architecture == "" {
+		return "", errors.New("image config has no os or architecture")
+	}
+
+	return config.String(), nil
+}
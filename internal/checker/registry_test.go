@@ -0,0 +1,145 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// fakeRegistryErrorRepo is a repo name which always returns an error from fakeRegistry
+	fakeRegistryErrorRepo = "error-repo"
+
+	// fakeRegistryDeniedRepo is a repo name whose tags can be checked but whose manifests are access denied
+	fakeRegistryDeniedRepo = "denied-repo"
+)
+
+// fakeRegistry is an in-process Registry used for testing
+type fakeRegistry struct {
+	mu     sync.Mutex
+	images map[string]map[string]Manifest
+
+	// configs maps the config digests of single manifests to their platform
+	configs map[string]string
+}
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{images: make(map[string]map[string]Manifest), configs: make(map[string]string)}
+}
+
+func (f *fakeRegistry) addConfig(digest, platform string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.configs[digest] = platform
+}
+
+func (f *fakeRegistry) addImage(repoName, tag string, m Manifest) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.images[repoName] == nil {
+		f.images[repoName] = make(map[string]Manifest)
+	}
+	f.images[repoName][tag] = m
+}
+
+func (f *fakeRegistry) TagExists(_ context.Context, repoName, tag string) (bool, error) {
+	if repoName == fakeRegistryErrorRepo {
+		return false, errors.New("fake registry error")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, ok := f.images[repoName][tag]
+	return ok, nil
+}
+
+func (f *fakeRegistry) ListTags(_ context.Context, repoName string) ([]string, error) {
+	if repoName == fakeRegistryErrorRepo {
+		return nil, errors.New("fake registry error")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tags := make([]string, 0)
+	for tag := range f.images[repoName] {
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+func (f *fakeRegistry) DescribeManifest(_ context.Context, repoName, tag string) (Manifest, error) {
+	if repoName == fakeRegistryErrorRepo {
+		return Manifest{}, errors.New("fake registry error")
+	}
+	if repoName == fakeRegistryDeniedRepo {
+		return Manifest{}, fmt.Errorf("getting %s:%s: %w", repoName, tag, &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "not authorized to perform ecr:BatchGetImage"})
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	m, ok := f.images[repoName][tag]
+	if !ok {
+		return Manifest{}, fmt.Errorf("%s:%s: %w", repoName, tag, ErrManifestNotFound)
+	}
+	return m, nil
+}
+
+func (f *fakeRegistry) ConfigPlatform(_ context.Context, _, digest string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	platform, ok := f.configs[digest]
+	if !ok {
+		return "", fmt.Errorf("config blob %s not found", digest)
+	}
+	return platform, nil
+}
+
+func Test_parseManifest(t *testing.T) {
+	index := `{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.oci.image.index.v1+json",
+  "manifests": [
+    {"digest": "sha256:aaa", "platform": {"architecture": "amd64", "os": "linux"}},
+    {"digest": "sha256:bbb", "platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}},
+    {"digest": "sha256:ccc", "platform": {"architecture": "unknown", "os": "unknown"}}
+  ]
+}`
+
+	m, err := parseManifest("", "sha256:123", []byte(index))
+	require.NoError(t, err)
+	require.Equal(t, mediaTypeOCIIndex, m.MediaType)
+	require.Equal(t, "sha256:123", m.Digest)
+	require.Equal(t, []string{"linux/amd64", "linux/arm64/v8"}, m.Platforms, "attestation manifests should be ignored")
+
+	m, err = parseManifest(mediaTypeDockerManifest, "sha256:456", []byte(`{"schemaVersion": 2, "config": {"digest": "sha256:789"}}`))
+	require.NoError(t, err)
+	require.Equal(t, mediaTypeDockerManifest, m.MediaType, "media type should fall back to the passed value")
+	require.Empty(t, m.Platforms)
+	require.Equal(t, "sha256:789", m.ConfigDigest)
+
+	_, err = parseManifest("", "", []byte("not-json"))
+	require.Error(t, err)
+}
+
+func Test_parseConfigPlatform(t *testing.T) {
+	platform, err := parseConfigPlatform([]byte(`{"architecture": "arm64", "os": "linux", "variant": "v8", "rootfs": {}}`))
+	require.NoError(t, err)
+	require.Equal(t, "linux/arm64/v8", platform)
+
+	_, err = parseConfigPlatform([]byte(`{"rootfs": {}}`))
+	require.Error(t, err)
+
+	_, err = parseConfigPlatform([]byte("not-json"))
+	require.Error(t, err)
+}
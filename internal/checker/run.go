@@ -1,20 +1,22 @@
 package checker
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
-	"github.com/aws/aws-sdk-go-v2/service/ecr"
-	ecrTypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"gopkg.in/yaml.v3"
 )
@@ -23,148 +25,402 @@ const (
 	defaultConfigFile = "config-defaults.yml"
 	childConfigFile   = "config.yml"
 	appName           = "ecr-image-checker"
+
+	// DefaultConcurrency is the default number of targets checked against the registries in parallel
+	DefaultConcurrency = 10
+
+	registryTypeECR       = "ecr"
+	registryTypeECRPublic = "ecr-public"
+	registryTypeOCI       = "oci"
+	registryTypeGHCR      = "ghcr"
+
+	repoTagStrategyStatic      = "static"
+	repoTagStrategyContentHash = "content-hash"
 )
 
+// awsAccountIDPattern matches a 12 digit AWS account ID. Shared with the JSON Schema
+var awsAccountIDPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
 type Target struct {
-	AwsAccountId *string `yaml:"aws_account_id" json:"aws_account_id"`
-	AwsRegion    *string `yaml:"aws_region" json:"aws_region"`
-	AwsRoleName  *string `yaml:"aws_role_name" json:"aws_role_name"`
+	AwsAccountId *string `yaml:"aws_account_id,omitempty" json:"aws_account_id"`
+	AwsRegion    *string `yaml:"aws_region,omitempty" json:"aws_region"`
+	AwsRoleName  *string `yaml:"aws_role_name,omitempty" json:"aws_role_name"`
+
+	// Non-ECR registries. Defaults to ECR if registry_type is not set
+	RegistryType        *string `yaml:"registry_type,omitempty" json:"registry_type"`
+	RegistryHost        *string `yaml:"registry_host,omitempty" json:"registry_host"`
+	RegistryNamespace   *string `yaml:"registry_namespace,omitempty" json:"registry_namespace"`
+	RegistryUsername    *string `yaml:"registry_username,omitempty" json:"registry_username"`
+	RegistryPasswordEnv *string `yaml:"registry_password_env,omitempty" json:"registry_password_env"`
+	RegistryInsecure    *bool   `yaml:"registry_insecure,omitempty" json:"registry_insecure"`
+
+	// Overrides of the repo level values for this target only. target_platforms replaces the repo's list, while
+	// build_args are merged by key over the repo's
+	TargetPlatforms []string          `yaml:"target_platforms,omitempty" json:"target_platforms_slice,omitempty"`
+	BuildArgs       map[string]string `yaml:"build_args,omitempty" json:"build_args_map,omitempty"`
 
 	// Calculated fields not passed via YAML
-	AWSRoleARN        string `json:"aws_role_arn"`
-	FullImageRef      string `json:"full_image_ref"`
-	RemoteTagMissing  bool   `json:"remote_tag_missing"`
-	WorkingDirectory  string `json:"working_directory"`
-	TargetPlatformStr string `json:"target_platforms"`
-	BuildArgsStr      string `json:"build_args"`
+	AWSRoleARN        string `yaml:"-" json:"aws_role_arn"`
+	FullImageRef      string `yaml:"-" json:"full_image_ref"`
+	RemoteTagMissing  bool   `yaml:"-" json:"remote_tag_missing"`
+	BuildReason       string `yaml:"-" json:"build_reason"`
+	WorkingDirectory  string `yaml:"-" json:"working_directory"`
+	TargetPlatformStr string `yaml:"-" json:"target_platforms"`
+	BuildArgsStr      string `yaml:"-" json:"build_args"`
+
+	// Environment is the name of the environment the target was referenced from, if any
+	Environment string `yaml:"-" json:"environment,omitempty"`
+
+	// Tags has an entry per tag of the image, in the order configured. FullImageRef is the ref of the first
+	Tags       []TagStatus `yaml:"-" json:"tags"`
+	TagArgsStr string      `yaml:"-" json:"tag_args"`
+
+	// A reference to an environment, such as targets: [prod], decodes to a placeholder until it is expanded.
+	// refIndex is the position of the reference in the config file, for reporting undefined environments
+	environmentRef   string
+	isEnvironmentRef bool
+	refIndex         int
+}
+
+// TagStatus is one of the image's tags in a target's registry, flagged if it needs to be pushed
+type TagStatus struct {
+	Tag          string `json:"tag"`
+	FullImageRef string `json:"full_image_ref"`
+	Missing      bool   `json:"missing"`
 }
 
 type repoConfig struct {
 	// Defaults, which can be overridden in the Targets
-	DefaultAwsAccountId *string `yaml:"default_aws_account_id" json:"default_aws_account_id"`
-	DefaultRegion       *string `yaml:"default_aws_region" json:"default_aws_region"`
-	DefaultAwsRoleName  *string `yaml:"default_aws_role_name" json:"default_aws_role_name"`
-
-	RepoName        *string           `yaml:"repo_name" json:"repo_name"`
-	RepoTag         *string           `yaml:"repo_tag" json:"repo_tag"`
-	TargetPlatforms []string          `yaml:"target_platforms" json:"target_platforms_slice"`
-	BuildArgs       map[string]string `yaml:"build_args" json:"build_args_map"`
-	Targets         []*Target         `yaml:"targets" json:"targets"`
+	DefaultAwsAccountId *string `yaml:"default_aws_account_id,omitempty" json:"default_aws_account_id"`
+	DefaultRegion       *string `yaml:"default_aws_region,omitempty" json:"default_aws_region"`
+	DefaultAwsRoleName  *string `yaml:"default_aws_role_name,omitempty" json:"default_aws_role_name"`
+
+	RepoName        *string           `yaml:"repo_name,omitempty" json:"repo_name"`
+	RepoTag         *string           `yaml:"repo_tag,omitempty" json:"repo_tag"`
+	RepoTags        []string          `yaml:"repo_tags,omitempty" json:"repo_tags"`
+	RepoTagStrategy *string           `yaml:"repo_tag_strategy,omitempty" json:"repo_tag_strategy"`
+	TargetPlatforms []string          `yaml:"target_platforms,omitempty" json:"target_platforms_slice"`
+	BuildArgs       map[string]string `yaml:"build_args,omitempty" json:"build_args_map"`
+	Targets         []*Target         `yaml:"targets,omitempty" json:"targets"`
+
+	// Environments are named sets of targets which can be referenced by name from targets. Only valid in defaults files
+	Environments map[string][]*Target `yaml:"environments,omitempty" json:"environments,omitempty"`
+
+	// Ignore lists globs of image directories to skip. Only valid in defaults files
+	Ignore []string `yaml:"ignore,omitempty" json:"ignore,omitempty"`
+}
+
+// Options configures a Run
+type Options struct {
+	// ImageDirectory is the base directory scanned for child config files
+	ImageDirectory string
+
+	// Concurrency is the maximum number of targets checked in parallel
+	Concurrency int
+
+	// SinceRef limits the check to image directories changed since this git ref, if set
+	SinceRef string
+
+	// UntilRef is the git ref changes are compared up to. Defaults to HEAD
+	UntilRef string
+
+	// DefaultsFile is the path of the root defaults file. Defaults to the nearest config-defaults.yml in the image
+	// directory or its parents
+	DefaultsFile string
+
+	// Format is the output format. Each command has its own default
+	Format string
+
+	// Output is where results are written. Defaults to stdout
+	Output io.Writer
+
+	// Environment only includes the targets referenced from the named environment of config-defaults.yml
+	Environment string
+
+	// Include limits the images to those whose directory, relative to ImageDirectory, matches one of the globs
+	Include []string
+
+	// Exclude skips the images whose directory, relative to ImageDirectory, matches one of the globs
+	Exclude []string
+
+	// AllowUnknownFields ignores unknown keys in the config files rather than rejecting them, for compatibility with
+	// config written for newer versions
+	AllowUnknownFields bool
+
+	// newRegistry replaces the registry client created for each target. Only set in tests
+	newRegistry func(target Target, repoName string) (Registry, error)
 }
 
 type config struct {
 	repos map[string]repoConfig
 
-	// AWS clients
-	stsClient *sts.Client
-	ecrClient ecr.ListImagesAPIClient
+	// Parsed YAML documents of the child config files, used to position errors
+	nodes map[string]*yaml.Node
+
+	// allowUnknownFields disables rejecting unknown keys in the config files
+	allowUnknownFields bool
+
+	// environmentNames are the environments defined by the defaults of any image
+	environmentNames map[string]bool
+
+	// include and exclude filter the discovered images. A nil include matches everything
+	include *dockerignore
+	exclude *dockerignore
+
+	// rootDefaultsFile is the absolute path of the root defaults file, which isn't layered again
+	rootDefaultsFile string
+
+	// directoryDefaultsCache holds the parsed nested defaults file of each directory, or nil if there isn't one
+	directoryDefaultsCache map[string]*repoConfig
+
+	// invalidDefaultsDirs are the directories whose nested defaults file failed to parse
+	invalidDefaultsDirs map[string]bool
+
+	// defaultsProblems are the problems with the values of the nested defaults files, found as each is first parsed
+	defaultsProblems ValidationErrors
+
+	// AWS clients, shared across all targets
+	awsClients *awsClientCache
+
+	// newRegistry creates the registry client for a target. Swapped out in tests
+	newRegistry func(target Target, repoName string) (Registry, error)
+}
+
+func newConfig() config {
+	c := config{
+		repos:            make(map[string]repoConfig),
+		nodes:            make(map[string]*yaml.Node),
+		environmentNames: make(map[string]bool),
+	}
+	c.newRegistry = c.setupRegistry
+
+	return c
 }
 
-func newConfig() (config, error) {
-	awsCfg, err := awsConfig.LoadDefaultConfig(context.Background())
+// connectAWS creates the AWS clients used by the ECR registries. This is deferred until the registries are checked
+// so that the config can be validated without AWS credentials
+func (c *config) connectAWS(ctx context.Context) error {
+	awsCfg, err := awsConfig.LoadDefaultConfig(ctx)
 	if err != nil {
-		return config{}, fmt.Errorf("loading AWS config: %w", err)
+		return fmt.Errorf("loading AWS config: %w", err)
 	}
 
-	// ECR client is initialized dynamically for each target account/region combo
+	// Registry clients are initialized dynamically for each target account/region/role combo
 	stsClient := sts.NewFromConfig(awsCfg)
+	c.awsClients = newAWSClientCache(awsCfg, stsClient)
 
-	c := config{
-		repos:     make(map[string]repoConfig),
-		stsClient: stsClient,
+	return nil
+}
+
+// Run checks every target and outputs those missing their tag as a GitHub Actions matrix
+func Run(opts Options) error {
+	format, err := opts.format(FormatGitHub, FormatJSON)
+	if err != nil {
+		return err
 	}
 
-	return c, nil
+	c, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	if err = c.checkRegistries(context.Background(), opts.Concurrency); err != nil {
+		return err
+	}
+
+	missingTags := filterMissingTags(c.repos)
+
+	if format == FormatJSON {
+		return writeJSON(opts.output(), missingTags)
+	}
+
+	output, err := outputGitHubJSON(missingTags)
+	if err != nil {
+		return fmt.Errorf("outputting GitHub JSON: %w", err)
+	}
+
+	// Output JSON to stdout which can be consumed by GitHub workflow matrix via an output
+	_, err = fmt.Fprintln(opts.output(), output)
+	return err
 }
 
-func Run(imageDirectory string) error {
+// loadConfig parses, validates and resolves the image config, filtering to the changed repos if a since ref is set
+func loadConfig(opts Options) (config, error) {
+	imageDirectory := opts.ImageDirectory
 	slog.Info("Base image directory", "path", imageDirectory)
 
-	c, err := newConfig()
-	if err != nil {
-		return fmt.Errorf("creating new config: %w", err)
+	if opts.Concurrency < 1 {
+		return config{}, fmt.Errorf("concurrency must be at least 1, got %d", opts.Concurrency)
 	}
 
-	// Parse default config file
-	defaultConfigData, err := parseYAMLFile(defaultConfigFile)
+	c := newConfig()
+	c.allowUnknownFields = opts.AllowUnknownFields
+	if opts.newRegistry != nil {
+		c.newRegistry = opts.newRegistry
+	}
+
+	var err error
+	if c.include, err = compileGlobs(opts.Include); err != nil {
+		return config{}, fmt.Errorf("compiling include globs: %w", err)
+	}
+	if c.exclude, err = compileGlobs(opts.Exclude); err != nil {
+		return config{}, fmt.Errorf("compiling exclude globs: %w", err)
+	}
+
+	// Parse default config file, found relative to the image directory rather than the working directory if not set
+	defaultsFile := opts.DefaultsFile
+	if defaultsFile == "" {
+		if defaultsFile, err = findDefaultsFile(imageDirectory); err != nil {
+			return config{}, err
+		}
+	}
+	slog.Info("Root defaults file", "path", defaultsFile)
+
+	if c.rootDefaultsFile, err = filepath.Abs(defaultsFile); err != nil {
+		return config{}, fmt.Errorf("resolving path %s: %w", defaultsFile, err)
+	}
+
+	defaultConfigData, defaultsNode, err := parseYAMLFile(defaultsFile, opts.AllowUnknownFields)
 	if err != nil {
-		return fmt.Errorf("parsing default YAML file (%s): %w", defaultConfigFile, err)
+		return config{}, fmt.Errorf("parsing default YAML file (%s): %w", defaultsFile, err)
 	}
+	c.nodes[defaultsFile] = defaultsNode
+
+	// Problems with individual config files are collected so that they are all reported together
+	var problems, more ValidationErrors
 
 	// Parse individual image directories
 	if err = c.parseChildConfig(imageDirectory, defaultConfigData); err != nil {
-		return fmt.Errorf("parsing child YAML files under %s: %w", imageDirectory, err)
+		if !errors.As(err, &problems) {
+			return config{}, fmt.Errorf("parsing child YAML files under %s: %w", imageDirectory, err)
+		}
 	}
 
-	if err = c.validate(); err != nil {
-		return fmt.Errorf("validating config: %w", err)
-	}
+	// The defaults files are validated once each, rather than through every image inheriting from them
+	problems = append(problems, c.validateValues(defaultsFile, defaultConfigData)...)
+	problems = append(problems, c.defaultsProblems...)
 
-	c.addCalculatedFields()
+	// Templates are rendered first, so content hashes are appended to the rendered tags
+	if err = c.renderTagTemplates(); err != nil {
+		if !errors.As(err, &more) {
+			return config{}, fmt.Errorf("rendering repo tags: %w", err)
+		}
+		problems = append(problems, more...)
+	}
 
-	for key, repo := range c.repos {
-		for idx, target := range repo.Targets {
-			if err = c.setupECRClient(*target, *repo.RepoName); err != nil {
-				return fmt.Errorf("setting up ECR client: %w", err)
-			}
+	if err = c.resolveRepoTags(); err != nil {
+		return config{}, fmt.Errorf("resolving repo tags: %w", err)
+	}
 
-			if err = c.checkECRImageTags(key, idx, repo, target); err != nil {
-				return fmt.Errorf("checking remote ECR Docker tags: %w", err)
-			}
+	if err = c.validate(); err != nil {
+		if !errors.As(err, &more) {
+			return config{}, fmt.Errorf("validating config: %w", err)
 		}
+		problems = append(problems, more...)
 	}
 
-	missingTags := filterMissingTags(c.repos)
+	if len(problems) > 0 {
+		return config{}, fmt.Errorf("validating config: %w", problems.sorted())
+	}
 
-	output, err := outputGitHubJSON(missingTags)
-	if err != nil {
-		return fmt.Errorf("outputting GitHub JSON: %w", err)
+	if opts.Environment != "" {
+		if err = c.selectEnvironment(opts.Environment); err != nil {
+			return config{}, err
+		}
 	}
 
-	// Output JSON to stdout which can be consumed by GitHub workflow matrix via an output
-	fmt.Println(output)
+	c.addCalculatedFields()
 
-	return nil
+	if opts.SinceRef != "" {
+		untilRef := opts.UntilRef
+		if untilRef == "" {
+			untilRef = "HEAD"
+		}
+
+		if err = c.filterChangedRepos(imageDirectory, opts.SinceRef, untilRef); err != nil {
+			return config{}, fmt.Errorf("detecting changed image directories: %w", err)
+		}
+	}
+
+	return c, nil
 }
 
 func (c *config) parseChildConfig(imageDirectory string, defaultConfigData repoConfig) error {
-	var sourceConfigFilePath string
 	var finalConfigData *repoConfig
+	problems := make(ValidationErrors, 0)
+
+	configPaths, err := discoverConfigFiles(imageDirectory)
+	if err != nil {
+		return err
+	}
 
-	baseDirectories, err := os.ReadDir(imageDirectory)
+	rootIgnore, err := compileGlobs(defaultConfigData.Ignore)
 	if err != nil {
-		return fmt.Errorf("reading directories in %s: %w", imageDirectory, err)
+		return fmt.Errorf("compiling ignore patterns of the root defaults: %w", err)
 	}
 
-	for _, baseDir := range baseDirectories {
-		// Ignore plain files and hidden directories
-		if !baseDir.IsDir() || strings.HasPrefix(baseDir.Name(), ".") {
+	for _, sourceConfigFilePath := range configPaths {
+		imageDir := path.Dir(sourceConfigFilePath)
+
+		selected, err := c.selected(imageDirectory, imageDir, rootIgnore)
+		if err != nil {
+			// The problems with an invalid defaults file are reported once by loadConfig, rather than for each image
+			if !errors.Is(err, errInvalidDefaults) {
+				return fmt.Errorf("filtering %s: %w", sourceConfigFilePath, err)
+			}
+			continue
+		}
+		if !selected {
 			continue
 		}
 
-		sourceConfigFilePath = path.Join(imageDirectory, baseDir.Name(), childConfigFile)
-
-		childConfigData, err := parseYAMLFile(sourceConfigFilePath)
+		childConfigData, node, err := parseYAMLFile(sourceConfigFilePath, c.allowUnknownFields)
 		if err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				slog.Warn("Skipping directory as child config file doesn't exist", "path", sourceConfigFilePath)
-				continue
-			} else {
+			var yamlProblems ValidationErrors
+			if !errors.As(err, &yamlProblems) {
 				return fmt.Errorf("parsing YAML file (%s): %w", sourceConfigFilePath, err)
 			}
+
+			// Keep going so the problems with every file are reported together
+			problems = append(problems, yamlProblems...)
+			continue
+		}
+
+		if len(childConfigData.Environments) > 0 {
+			line, column := nodePosition(node, "environments")
+			problems = append(problems, &ValidationError{Path: sourceConfigFilePath, Line: line, Column: column, Message: fmt.Sprintf("environments is only supported in %s", defaultConfigFile)})
+			continue
 		}
 
 		// Check that a Dockerfile exists alongside the config file as the pipeline expects one
-		dockerfilePath := path.Join(imageDirectory, baseDir.Name(), "Dockerfile")
+		dockerfilePath := path.Join(imageDir, "Dockerfile")
 		_, err = os.ReadFile(dockerfilePath)
 		if err != nil && errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("unable to find Dockerfile %s alongside child config file %s", dockerfilePath, sourceConfigFilePath)
+			problems = append(problems, &ValidationError{Path: sourceConfigFilePath, Message: fmt.Sprintf("unable to find Dockerfile %s alongside child config file", dockerfilePath)})
+			continue
 		}
 
 		slog.Info("Found child config file", "path", sourceConfigFilePath)
 
+		// Values are checked before merging, so only those set in the child are positioned against it
+		c.nodes[sourceConfigFilePath] = node
+		problems = append(problems, c.validateValues(sourceConfigFilePath, childConfigData)...)
+
+		// Nested defaults files between the base directory and the image directory layer over the root defaults
+		imageDefaults, err := c.layeredDefaults(imageDirectory, imageDir, defaultConfigData)
+		if err != nil {
+			if !errors.Is(err, errInvalidDefaults) {
+				return fmt.Errorf("layering defaults for %s: %w", sourceConfigFilePath, err)
+			}
+			continue
+		}
+
+		for name := range imageDefaults.Environments {
+			c.environmentNames[name] = true
+		}
+
 		// Merge the child config over the default config to determine the final config for this image
-		finalConfigData = mergeRepoConfig(&defaultConfigData, &childConfigData)
+		finalConfigData = mergeRepoConfig(&imageDefaults, &childConfigData)
 
 		c.repos[sourceConfigFilePath] = *finalConfigData
 
@@ -175,101 +431,267 @@ func (c *config) parseChildConfig(imageDirectory string, defaultConfigData repoC
 				"aws_account_id", readStrPointer(target.AwsAccountId),
 				"aws_role_name", readStrPointer(target.AwsRoleName),
 				"repo_name", readStrPointer(finalConfigData.RepoName),
-				"repo_tag", readStrPointer(finalConfigData.RepoTag),
+				"repo_tags", finalConfigData.tags(),
 			)
 		}
+	}
+
+	return problems.orNil()
+}
+
+func (c *config) setupRegistry(target Target, repoName string) (Registry, error) {
+	switch target.registryType() {
+	case registryTypeOCI:
+		r, err := newOCIRegistry(target)
+		if err != nil {
+			return nil, fmt.Errorf("creating OCI registry client: %w", err)
+		}
+		return r, nil
+	case registryTypeGHCR:
+		r, err := newGHCRRegistry(target)
+		if err != nil {
+			return nil, fmt.Errorf("creating GHCR registry client: %w", err)
+		}
+		return r, nil
+	case registryTypeECRPublic:
+		r, err := newECRPublicRegistry(c.awsClients, target, repoName)
+		if err != nil {
+			return nil, fmt.Errorf("creating ECR Public registry client: %w", err)
+		}
+		return r, nil
+	default:
+		return newECRRegistry(c.awsClients, target, repoName), nil
+	}
+}
+
+// validate checks every repo, returning all the problems found as ValidationErrors rather than stopping at the first
+func (c *config) validate() error {
+	problems := make(ValidationErrors, 0)
 
+	for _, key := range sortedKeys(c.repos) {
+		problems = append(problems, c.validateRepo(key, c.repos[key])...)
 	}
 
-	return nil
+	return problems.orNil()
 }
 
-func (c *config) setupECRClient(target Target, repoName string) error {
-	awsCfg, err := awsConfig.LoadDefaultConfig(context.Background(), func(o *awsConfig.LoadOptions) error {
-		o.Region = *target.AwsRegion
-		return nil
-	})
-	if err != nil {
-		return fmt.Errorf("loading AWS config: %w", err)
+// validateValues checks the values set in a single config or defaults file, positioned against that file. Inherited
+// values are checked once in the file which sets them, rather than for every image using them
+func (c *config) validateValues(key string, rc repoConfig) ValidationErrors {
+	errs := make(ValidationErrors, 0)
+
+	if !strPtrEmpty(rc.DefaultAwsAccountId) && !awsAccountIDPattern.MatchString(*rc.DefaultAwsAccountId) {
+		errs = append(errs, c.errorAt(key, fmt.Sprintf("default_aws_account_id %s must be a 12 digit AWS account ID", *rc.DefaultAwsAccountId), "default_aws_account_id"))
 	}
 
-	// The value might be empty if we want to override a role name being set at the default level
-	if target.AwsRoleName != nil && *target.AwsRoleName != "" {
-		slog.Debug("Assuming role", "role", target.AWSRoleARN, "repo", repoName)
-		creds := stscreds.NewAssumeRoleProvider(c.stsClient, target.AWSRoleARN, func(o *stscreds.AssumeRoleOptions) {
-			o.RoleSessionName = appName
-		})
-		awsCfg.Credentials = aws.NewCredentialsCache(creds)
-		c.ecrClient = ecr.NewFromConfig(awsCfg)
+	switch readStrPointer(rc.RepoTagStrategy) {
+	case "", repoTagStrategyStatic, repoTagStrategyContentHash:
+	default:
+		errs = append(errs, c.errorAt(key, fmt.Sprintf("unknown repo_tag_strategy %s", *rc.RepoTagStrategy), "repo_tag_strategy"))
+	}
 
-		return nil
+	for idx, tag := range rc.RepoTags {
+		if tag == "" {
+			errs = append(errs, c.errorAt(key, fmt.Sprintf("repo_tags cannot contain empty values, index %d", idx), "repo_tags", idx))
+		}
 	}
 
-	slog.Debug("No assume IAM role defined. Using normal credential chain", "repo", repoName)
-	c.ecrClient = ecr.NewFromConfig(awsCfg)
+	for idx, targetPlatform := range rc.TargetPlatforms {
+		if targetPlatform == "" {
+			errs = append(errs, c.errorAt(key, fmt.Sprintf("target_platforms cannot contain empty values, index %d", idx), "target_platforms", idx))
+		}
+	}
 
-	return nil
+	if rc.BuildArgs != nil && len(rc.BuildArgs) == 0 {
+		errs = append(errs, c.errorAt(key, "build_args must have at one key/pair when defined", "build_args"))
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(rc.BuildArgs)) {
+		if strings.TrimSpace(rc.BuildArgs[k]) == "" {
+			errs = append(errs, c.errorAt(key, fmt.Sprintf("build_args must have no empty values, key %s", k), "build_args", k))
+		}
+	}
+
+	for idx, target := range rc.Targets {
+		if target.isEnvironmentRef {
+			continue
+		}
+		errs = append(errs, c.validateTargetValues(key, target, fmt.Sprintf("target index %d", idx), "targets", idx)...)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(rc.Environments)) {
+		for idx, target := range rc.Environments[name] {
+			errs = append(errs, c.validateTargetValues(key, target, fmt.Sprintf("target index %d of environment %s", idx, name), "environments", name, idx)...)
+		}
+	}
+
+	return errs
 }
 
-func (c *config) validate() error {
-	for key, repo := range c.repos {
-		if strPtrEmpty(repo.RepoName) {
-			return fmt.Errorf("repo_name not set for %s", key)
+// validateTargetValues checks the values set on a single target. where describes the target in the messages, and
+// nodePath is the position of the target in the file
+func (c *config) validateTargetValues(key string, target *Target, where string, nodePath ...any) ValidationErrors {
+	errs := make(ValidationErrors, 0)
+	at := func(elems ...any) []any {
+		return append(slices.Clone(nodePath), elems...)
+	}
+
+	for i, targetPlatform := range target.TargetPlatforms {
+		if targetPlatform == "" {
+			errs = append(errs, c.errorAt(key, fmt.Sprintf("target_platforms cannot contain empty values, index %d for %s", i, where), at("target_platforms", i)...))
 		}
+	}
 
-		if strPtrEmpty(repo.RepoTag) {
-			return fmt.Errorf("repo_tag not set for %s", key)
+	if target.BuildArgs != nil && len(target.BuildArgs) == 0 {
+		errs = append(errs, c.errorAt(key, fmt.Sprintf("build_args must have at one key/pair when defined for %s", where), at("build_args")...))
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(target.BuildArgs)) {
+		if strings.TrimSpace(target.BuildArgs[k]) == "" {
+			errs = append(errs, c.errorAt(key, fmt.Sprintf("build_args must have no empty values, key %s for %s", k, where), at("build_args", k)...))
 		}
+	}
+
+	if !strPtrEmpty(target.AwsAccountId) && !awsAccountIDPattern.MatchString(*target.AwsAccountId) {
+		errs = append(errs, c.errorAt(key, fmt.Sprintf("aws_account_id %s must be a 12 digit AWS account ID for %s", *target.AwsAccountId, where), at("aws_account_id")...))
+	}
+
+	switch target.registryType() {
+	case registryTypeECR, registryTypeECRPublic, registryTypeOCI, registryTypeGHCR:
+	default:
+		errs = append(errs, c.errorAt(key, fmt.Sprintf("unknown registry_type %s for %s", target.registryType(), where), at("registry_type")...))
+	}
+
+	return errs
+}
+
+// validateRepo checks that the resolved config of an image is complete. The values themselves are checked by
+// validateValues in the file which sets them
+func (c *config) validateRepo(key string, repo repoConfig) ValidationErrors {
+	errs := make(ValidationErrors, 0)
+
+	if strPtrEmpty(repo.RepoName) {
+		errs = append(errs, c.errorAt(key, "repo_name not set", "repo_name"))
+	}
 
-		if repo.Targets == nil || len(repo.Targets) == 0 {
-			return fmt.Errorf("targets not set for %s either at the child level or via defaults", key)
+	if len(repo.Ignore) > 0 {
+		errs = append(errs, c.errorAt(key, fmt.Sprintf("ignore is only supported in %s", defaultConfigFile), "ignore"))
+	}
+
+	if strPtrEmpty(repo.RepoTag) && len(repo.RepoTags) == 0 {
+		errs = append(errs, c.errorAt(key, "repo_tag not set", "repo_tag"))
+	}
+
+	// Duplicates are checked once the tags are rendered, as different templates can render the same tag
+	seenTags := make(map[string]bool)
+	if !strPtrEmpty(repo.RepoTag) {
+		seenTags[*repo.RepoTag] = true
+	}
+	for idx, tag := range repo.RepoTags {
+		if tag == "" {
+			continue
 		}
 
-		if repo.TargetPlatforms == nil || len(repo.TargetPlatforms) == 0 {
-			return fmt.Errorf("target_platforms not set for %s", key)
+		if seenTags[tag] {
+			errs = append(errs, c.errorAt(key, fmt.Sprintf("repo_tags contains duplicate tag %s, index %d", tag, idx), "repo_tags", idx))
 		}
+		seenTags[tag] = true
+	}
+
+	if len(repo.Targets) == 0 {
+		errs = append(errs, c.errorAt(key, "targets not set either at the child level or via defaults", "targets"))
+	}
 
-		for idx, targetPlatform := range repo.TargetPlatforms {
-			if targetPlatform == "" {
-				return fmt.Errorf("target_platforms cannot contain empty values for %s index %d", key, idx)
+	// Repo level platforms are only needed by targets which don't set their own
+	if len(repo.TargetPlatforms) == 0 && !slices.ContainsFunc(repo.Targets, func(t *Target) bool { return len(t.TargetPlatforms) > 0 }) {
+		errs = append(errs, c.errorAt(key, "target_platforms not set", "target_platforms"))
+	} else if len(repo.TargetPlatforms) == 0 {
+		for idx, target := range repo.Targets {
+			if len(target.TargetPlatforms) == 0 && !target.isEnvironmentRef {
+				errs = append(errs, c.errorAt(key, fmt.Sprintf("target_platforms not set for target index %d and there is no repo level default", idx), "targets", idx))
 			}
 		}
+	}
 
-		if repo.BuildArgs != nil && len(repo.BuildArgs) == 0 {
-			return fmt.Errorf("build_args must have at one key/pair when defined for %s", key)
+	// Check if the account ID and region are either set at the child target level or in the defaults
+	defaultAwsAccountIdSet := !strPtrEmpty(repo.DefaultAwsAccountId)
+	defaultAwsRegionSet := !strPtrEmpty(repo.DefaultRegion)
+
+	for idx, target := range repo.Targets {
+		if target.isEnvironmentRef {
+			errs = append(errs, c.errorAt(key, fmt.Sprintf("environment %s referenced by target index %d is not defined in %s", target.environmentRef, target.refIndex, defaultConfigFile), "targets", target.refIndex))
+			continue
 		}
 
-		for k, arg := range repo.BuildArgs {
-			if strings.TrimSpace(arg) == "" {
-				return fmt.Errorf("build_args must have no empty values for %s key %s", key, k)
+		switch target.registryType() {
+		case registryTypeECR:
+			if strPtrEmpty(target.AwsAccountId) && !defaultAwsAccountIdSet {
+				errs = append(errs, c.errorAt(key, fmt.Sprintf("aws_account_id not set for target index %d and there is no default set", idx), "targets", idx, "aws_account_id"))
+			}
+
+			if strPtrEmpty(target.AwsRegion) && !defaultAwsRegionSet {
+				errs = append(errs, c.errorAt(key, fmt.Sprintf("aws_region not set for target index %d and there is no default set", idx), "targets", idx, "aws_region"))
+			}
+		case registryTypeOCI:
+			if strPtrEmpty(target.RegistryHost) {
+				errs = append(errs, c.errorAt(key, fmt.Sprintf("registry_host not set for target index %d", idx), "targets", idx, "registry_host"))
+			}
+		case registryTypeGHCR:
+			if strPtrEmpty(target.RegistryNamespace) {
+				errs = append(errs, c.errorAt(key, fmt.Sprintf("registry_namespace not set for target index %d", idx), "targets", idx, "registry_namespace"))
+			}
+		case registryTypeECRPublic:
+			if strPtrEmpty(target.RegistryNamespace) {
+				errs = append(errs, c.errorAt(key, fmt.Sprintf("registry_namespace not set to the ECR Public alias for target index %d", idx), "targets", idx, "registry_namespace"))
+			}
+			if !strPtrEmpty(target.AwsRoleName) && strPtrEmpty(target.AwsAccountId) && !defaultAwsAccountIdSet {
+				errs = append(errs, c.errorAt(key, fmt.Sprintf("aws_account_id not set for target index %d which assumes a role and there is no default set", idx), "targets", idx, "aws_account_id"))
 			}
 		}
+	}
 
-		// Check if the account ID and region are either set at the child target level or in the defaults
-		var defaultAwsAccountIdSet bool
-		var defaultAwsRegionSet bool
+	return errs
+}
 
-		if repo.DefaultAwsAccountId != nil && len(*repo.DefaultAwsAccountId) > 0 {
-			defaultAwsAccountIdSet = true
+// resolveRepoTags computes the tags of the repos using the content-hash strategy. Any repo_tag set is used as a prefix
+func (c *config) resolveRepoTags() error {
+	for key, repo := range c.repos {
+		if readStrPointer(repo.RepoTagStrategy) != repoTagStrategyContentHash {
+			continue
 		}
 
-		if repo.DefaultRegion != nil && len(*repo.DefaultRegion) > 0 {
-			defaultAwsRegionSet = true
+		// Every target shares the tag, so the build args of any target which overrides them are hashed too
+		buildArgs := []map[string]string{repo.BuildArgs}
+		for _, target := range repo.Targets {
+			if len(target.BuildArgs) > 0 {
+				buildArgs = append(buildArgs, target.buildArgs(repo))
+			}
 		}
 
-		for idx, target := range repo.Targets {
-			if target.AwsAccountId == nil || len(*target.AwsAccountId) == 0 {
-				if !defaultAwsAccountIdSet {
-					return fmt.Errorf("aws_account_id not set for %s target index %d and there is no default set", key, idx)
-				}
+		hash, err := contentHash(path.Dir(key), buildArgs...)
+		if err != nil {
+			return fmt.Errorf("computing content hash for %s: %w", key, err)
+		}
+
+		// Each configured tag is used as a prefix of the hash
+		hash = hash[:contentHashLength]
+		if strPtrEmpty(repo.RepoTag) && len(repo.RepoTags) == 0 {
+			repo.RepoTag = &hash
+		} else {
+			if !strPtrEmpty(repo.RepoTag) {
+				tag := fmt.Sprintf("%s-%s", *repo.RepoTag, hash)
+				repo.RepoTag = &tag
 			}
 
-			if target.AwsRegion == nil || len(*target.AwsRegion) == 0 {
-				if !defaultAwsRegionSet {
-					return fmt.Errorf("aws_region not set for %s target index %d and there is no default set", key, idx)
-				}
+			tags := make([]string, 0, len(repo.RepoTags))
+			for _, tag := range repo.RepoTags {
+				tags = append(tags, fmt.Sprintf("%s-%s", tag, hash))
 			}
+			repo.RepoTags = tags
 		}
+		slog.Debug("Computed content hash tags", "path", key, "tags", repo.tags())
+
+		c.repos[key] = repo
 	}
 
 	return nil
@@ -278,26 +700,42 @@ func (c *config) validate() error {
 func (c *config) addCalculatedFields() {
 	for key, repo := range c.repos {
 		for _, target := range repo.Targets {
-			if target.AwsRoleName != nil && len(*target.AwsRoleName) > 0 {
+			// Always output the resolved registry type so the build job can branch on it
+			registryType := target.registryType()
+			target.RegistryType = &registryType
+
+			if target.isAWSRegistry() && target.AwsRoleName != nil && len(*target.AwsRoleName) > 0 {
 				target.AWSRoleARN = fmt.Sprintf("arn:aws:iam::%s:role/%s", *target.AwsAccountId, *target.AwsRoleName)
 			}
 
-			target.FullImageRef = fmt.Sprintf("%s.dkr.ecr.%s.amazonaws.com/%s:%s", *target.AwsAccountId, *target.AwsRegion, *repo.RepoName, *repo.RepoTag)
+			target.Tags = make([]TagStatus, 0, len(repo.tags()))
+			tagArgs := make([]string, 0, len(repo.tags()))
+			for _, tag := range repo.tags() {
+				ref := target.imageRef(*repo.RepoName, tag)
+				target.Tags = append(target.Tags, TagStatus{Tag: tag, FullImageRef: ref})
+				tagArgs = append(tagArgs, "-t "+ref)
+			}
+			target.TagArgsStr = strings.Join(tagArgs, " ")
+
+			if len(target.Tags) > 0 {
+				target.FullImageRef = target.Tags[0].FullImageRef
+			}
 
 			target.WorkingDirectory = path.Dir(key)
 
-			if len(repo.TargetPlatforms) > 0 {
-				target.TargetPlatformStr = strings.Join(repo.TargetPlatforms, ",")
+			if platforms := target.platforms(repo); len(platforms) > 0 {
+				target.TargetPlatformStr = strings.Join(platforms, ",")
 			}
 
-			if len(repo.BuildArgs) > 0 {
+			buildArgs := target.buildArgs(repo)
+			if len(buildArgs) > 0 {
 				count := 0
-				for k, arg := range repo.BuildArgs {
+				for _, k := range slices.Sorted(maps.Keys(buildArgs)) {
 					if count > 0 {
 						target.BuildArgsStr += " "
 					}
 
-					target.BuildArgsStr += fmt.Sprintf("--build-arg %s=%s", k, arg)
+					target.BuildArgsStr += fmt.Sprintf("--build-arg %s=%s", k, buildArgs[k])
 
 					count++
 				}
@@ -307,65 +745,6 @@ func (c *config) addCalculatedFields() {
 	}
 }
 
-func (c *config) checkECRImageTags(key string, index int, repo repoConfig, target *Target) error {
-	remoteTagMissing := true
-	ecrImages := &ecr.ListImagesOutput{}
-	var err error
-	nextToken := ""
-
-	for {
-		listImagesInput := &ecr.ListImagesInput{
-			RepositoryName: repo.RepoName,
-			Filter:         &ecrTypes.ListImagesFilter{TagStatus: ecrTypes.TagStatusTagged},
-		}
-
-		if nextToken != "" {
-			listImagesInput.NextToken = aws.String(nextToken)
-		}
-
-		// If not using an IAM assume role we need to set which remote ECR registry to query
-		if target.AWSRoleARN == "" {
-			slog.Debug("No assume role so setting list images target registry", "registry", *target.AwsAccountId)
-			listImagesInput.RegistryId = target.AwsAccountId
-		}
-
-		ecrImages, err = c.ecrClient.ListImages(context.Background(), listImagesInput)
-		if err != nil {
-			return fmt.Errorf("listing ECR Docker tags for %s: %w", *repo.RepoName, err)
-		}
-
-		if ecrImages != nil {
-			for _, image := range ecrImages.ImageIds {
-				if image.ImageTag != nil && *image.ImageTag == *repo.RepoTag {
-					slog.Debug("Found image tag", "repo", *repo.RepoName, "tag", *repo.RepoTag)
-					remoteTagMissing = false
-					break
-				}
-			}
-		}
-
-		// Found remote image ref
-		if !remoteTagMissing {
-			break
-		}
-
-		// No more remote images to check
-		if ecrImages == nil || ecrImages.NextToken == nil {
-			break
-		}
-
-		nextToken = *ecrImages.NextToken
-	}
-
-	// Flag the Docker tag as needing to be built
-	if remoteTagMissing {
-		target.RemoteTagMissing = true
-		c.repos[key].Targets[index] = target
-	}
-
-	return nil
-}
-
 func outputGitHubJSON(missingTags []Target) (string, error) {
 	// No Docker images to build
 	if len(missingTags) == 0 {
@@ -380,57 +759,97 @@ func outputGitHubJSON(missingTags []Target) (string, error) {
 	return fmt.Sprintf("targets=%s\n", string(b)), nil
 }
 
-func parseYAMLFile(path string) (repoConfig, error) {
+// parseYAMLFile decodes the config file, also returning the YAML document so errors can be reported with positions.
+// Unknown keys are rejected unless allowUnknownFields is set. Problems with the YAML itself are returned as
+// ValidationErrors
+func parseYAMLFile(path string, allowUnknownFields bool) (repoConfig, *yaml.Node, error) {
 	configData := repoConfig{}
 
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return configData, fmt.Errorf("opening YAML file (%s): %w", path, err)
+		return configData, nil, fmt.Errorf("opening YAML file (%s): %w", path, err)
+	}
+
+	var node yaml.Node
+	if err = yaml.Unmarshal(data, &node); err != nil {
+		return configData, nil, yamlErrors(path, err)
+	}
+
+	// An empty file has no document to decode
+	if node.Kind == 0 {
+		return configData, &node, nil
+	}
+
+	// Values are decoded from the node once environment variables have been expanded
+	problems := interpolateEnv(path, &node)
+	if err = node.Decode(&configData); err != nil {
+		problems = append(problems, yamlErrors(path, err)...)
 	}
 
-	if err = yaml.Unmarshal(data, &configData); err != nil {
-		return configData, fmt.Errorf("parsing YAML file (%s): %w", path, err)
+	// Decoding from the node doesn't support rejecting unknown keys, so the file is decoded again to find them
+	if !allowUnknownFields {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err = dec.Decode(&repoConfig{}); err != nil && !errors.Is(err, io.EOF) {
+			problems = append(problems, unknownFieldErrors(path, err)...)
+		}
 	}
 
-	return configData, nil
+	if len(problems) > 0 {
+		return configData, nil, problems.sorted()
+	}
+
+	return configData, &node, nil
 }
 
 func mergeRepoConfig(defaultConf, childRepoConf *repoConfig) *repoConfig {
+	// Layer the child over the defaults the same as a nested defaults file, so any key can be defaulted
+	*childRepoConf = layerDefaults(*defaultConf, *childRepoConf)
+
+	// Environment references are expanded first, so their targets are completed by the defaults too
+	childRepoConf.Targets = expandEnvironments(childRepoConf.Targets, childRepoConf.Environments)
+	childRepoConf.Environments = nil
+
 	for _, target := range childRepoConf.Targets {
+		// AWS defaults are only relevant to ECR targets
+		if !target.isAWSRegistry() || target.isEnvironmentRef {
+			continue
+		}
+
 		if target.AwsAccountId == nil {
-			if defaultConf.DefaultAwsAccountId != nil && len(*defaultConf.DefaultAwsAccountId) > 0 {
-				target.AwsAccountId = defaultConf.DefaultAwsAccountId
-				slog.Debug("Using default config value", "repo", readStrPointer(childRepoConf.RepoName), "aws_account_id", readStrPointer(defaultConf.DefaultAwsAccountId))
+			if childRepoConf.DefaultAwsAccountId != nil && len(*childRepoConf.DefaultAwsAccountId) > 0 {
+				target.AwsAccountId = childRepoConf.DefaultAwsAccountId
+				slog.Debug("Using default config value", "repo", readStrPointer(childRepoConf.RepoName), "aws_account_id", readStrPointer(childRepoConf.DefaultAwsAccountId))
 			}
 		}
 
 		if target.AwsRegion == nil {
-			if defaultConf.DefaultRegion != nil && len(*defaultConf.DefaultRegion) > 0 {
-				target.AwsRegion = defaultConf.DefaultRegion
-				slog.Debug("Using default config value", "repo", readStrPointer(childRepoConf.RepoName), "aws_region", readStrPointer(defaultConf.DefaultRegion))
+			if childRepoConf.DefaultRegion != nil && len(*childRepoConf.DefaultRegion) > 0 {
+				target.AwsRegion = childRepoConf.DefaultRegion
+				slog.Debug("Using default config value", "repo", readStrPointer(childRepoConf.RepoName), "aws_region", readStrPointer(childRepoConf.DefaultRegion))
 			}
 		}
 
 		if target.AwsRoleName == nil {
-			if defaultConf.DefaultAwsRoleName != nil && len(*defaultConf.DefaultAwsRoleName) > 0 {
-				target.AwsRoleName = defaultConf.DefaultAwsRoleName
-				slog.Debug("Using default config value", "repo", readStrPointer(childRepoConf.RepoName), "aws_role_name", readStrPointer(defaultConf.DefaultAwsRoleName))
+			if childRepoConf.DefaultAwsRoleName != nil && len(*childRepoConf.DefaultAwsRoleName) > 0 {
+				target.AwsRoleName = childRepoConf.DefaultAwsRoleName
+				slog.Debug("Using default config value", "repo", readStrPointer(childRepoConf.RepoName), "aws_role_name", readStrPointer(childRepoConf.DefaultAwsRoleName))
 			}
 		}
 	}
 
 	// No targets key entirely -> fall back to defaults if available
 	if childRepoConf.Targets == nil || len(childRepoConf.Targets) == 0 {
-		if defaultConf.DefaultAwsAccountId != nil && defaultConf.DefaultRegion != nil {
+		if childRepoConf.DefaultAwsAccountId != nil && childRepoConf.DefaultRegion != nil {
 			childRepoConf.Targets = []*Target{
 				{
-					AwsAccountId: defaultConf.DefaultAwsAccountId,
-					AwsRegion:    defaultConf.DefaultRegion,
+					AwsAccountId: childRepoConf.DefaultAwsAccountId,
+					AwsRegion:    childRepoConf.DefaultRegion,
 				},
 			}
 
-			if defaultConf.DefaultAwsRoleName != nil {
-				childRepoConf.Targets[0].AwsRoleName = defaultConf.DefaultAwsRoleName
+			if childRepoConf.DefaultAwsRoleName != nil {
+				childRepoConf.Targets[0].AwsRoleName = childRepoConf.DefaultAwsRoleName
 			}
 
 			slog.Debug("Using default config value", "repo", readStrPointer(childRepoConf.RepoName), "targets", childRepoConf.Targets)
@@ -443,8 +862,9 @@ func mergeRepoConfig(defaultConf, childRepoConf *repoConfig) *repoConfig {
 func filterMissingTags(original map[string]repoConfig) []Target {
 	missingTags := make([]Target, 0)
 
-	for _, repo := range original {
-		for _, target := range repo.Targets {
+	// Iterate in a stable order so the output is deterministic between runs
+	for _, key := range sortedKeys(original) {
+		for _, target := range original[key].Targets {
 			if target.RemoteTagMissing {
 				missingTags = append(missingTags, *target)
 			}
@@ -454,6 +874,73 @@ func filterMissingTags(original map[string]repoConfig) []Target {
 	return missingTags
 }
 
+// registryType returns the configured registry type, defaulting to ECR
+func (t *Target) registryType() string {
+	if strPtrEmpty(t.RegistryType) {
+		return registryTypeECR
+	}
+	return *t.RegistryType
+}
+
+// imageRef returns the full reference of the image tag in the target's registry
+func (t *Target) imageRef(repoName, tag string) string {
+	switch t.registryType() {
+	case registryTypeOCI:
+		if !strPtrEmpty(t.RegistryNamespace) {
+			return fmt.Sprintf("%s/%s/%s:%s", *t.RegistryHost, *t.RegistryNamespace, repoName, tag)
+		}
+		return fmt.Sprintf("%s/%s:%s", *t.RegistryHost, repoName, tag)
+	case registryTypeGHCR:
+		return ghcrImageRef(*t, repoName, tag)
+	case registryTypeECRPublic:
+		return ecrPublicImageRef(*t, repoName, tag)
+	default:
+		return fmt.Sprintf("%s.dkr.ecr.%s.amazonaws.com/%s:%s", *t.AwsAccountId, *t.AwsRegion, repoName, tag)
+	}
+}
+
+// tags returns every tag of the image, repo_tag followed by repo_tags
+func (r repoConfig) tags() []string {
+	tags := make([]string, 0, len(r.RepoTags)+1)
+	if !strPtrEmpty(r.RepoTag) {
+		tags = append(tags, *r.RepoTag)
+	}
+
+	return append(tags, r.RepoTags...)
+}
+
+// platforms returns the platforms the target is built for. The target's own list replaces the repo's if set
+func (t *Target) platforms(repo repoConfig) []string {
+	if len(t.TargetPlatforms) > 0 {
+		return t.TargetPlatforms
+	}
+	return repo.TargetPlatforms
+}
+
+// buildArgs returns the build args of the target, which are the repo's with the target's own merged over them
+func (t *Target) buildArgs(repo repoConfig) map[string]string {
+	if len(t.BuildArgs) == 0 {
+		return repo.BuildArgs
+	}
+
+	merged := maps.Clone(repo.BuildArgs)
+	if merged == nil {
+		merged = make(map[string]string, len(t.BuildArgs))
+	}
+	maps.Copy(merged, t.BuildArgs)
+
+	return merged
+}
+
+// isAWSRegistry reports whether the target is either a private or public ECR registry
+func (t *Target) isAWSRegistry() bool {
+	return t.registryType() == registryTypeECR || t.registryType() == registryTypeECRPublic
+}
+
+func sortedKeys(repos map[string]repoConfig) []string {
+	return slices.Sorted(maps.Keys(repos))
+}
+
 func readStrPointer(ptr *string) string {
 	if ptr != nil {
 		return *ptr
@@ -1,7 +1,6 @@
 package checker
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -9,9 +8,8 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/arn"
-	"github.com/aws/aws-sdk-go-v2/service/ecr"
-	ecrTypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 func Test_validate(t *testing.T) {
@@ -234,6 +232,225 @@ func Test_validate(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			testName: "Unknown repo tag strategy",
+			keyName:  "image-1/config.yml",
+			conf: repoConfig{
+				DefaultAwsAccountId: aws.String(awsAccountID),
+				DefaultRegion:       aws.String(awsRegion),
+				RepoName:            aws.String(repoName),
+				RepoTag:             aws.String(tagName),
+				RepoTagStrategy:     aws.String("unknown"),
+				TargetPlatforms:     targetPlatforms,
+				Targets: []*Target{
+					{
+						AwsAccountId: aws.String(awsAccountID),
+						AwsRegion:    aws.String(awsRegion),
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			testName: "OCI target without AWS fields",
+			keyName:  "image-1/config.yml",
+			conf: repoConfig{
+				RepoName:        aws.String(repoName),
+				RepoTag:         aws.String(tagName),
+				TargetPlatforms: targetPlatforms,
+				Targets: []*Target{
+					{
+						RegistryType: aws.String(registryTypeOCI),
+						RegistryHost: aws.String("harbor.example.com"),
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			testName: "OCI target without registry host",
+			keyName:  "image-1/config.yml",
+			conf: repoConfig{
+				RepoName:        aws.String(repoName),
+				RepoTag:         aws.String(tagName),
+				TargetPlatforms: targetPlatforms,
+				Targets: []*Target{
+					{
+						RegistryType: aws.String(registryTypeOCI),
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			testName: "GHCR target without namespace",
+			keyName:  "image-1/config.yml",
+			conf: repoConfig{
+				RepoName:        aws.String(repoName),
+				RepoTag:         aws.String(tagName),
+				TargetPlatforms: targetPlatforms,
+				Targets: []*Target{
+					{
+						RegistryType: aws.String(registryTypeGHCR),
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			testName: "ECR Public target",
+			keyName:  "image-1/config.yml",
+			conf: repoConfig{
+				RepoName:        aws.String(repoName),
+				RepoTag:         aws.String(tagName),
+				TargetPlatforms: targetPlatforms,
+				Targets: []*Target{
+					{
+						RegistryType:      aws.String(registryTypeECRPublic),
+						RegistryNamespace: aws.String("my-alias"),
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			testName: "ECR Public target assuming role without account ID",
+			keyName:  "image-1/config.yml",
+			conf: repoConfig{
+				RepoName:        aws.String(repoName),
+				RepoTag:         aws.String(tagName),
+				TargetPlatforms: targetPlatforms,
+				Targets: []*Target{
+					{
+						RegistryType:      aws.String(registryTypeECRPublic),
+						RegistryNamespace: aws.String("my-alias"),
+						AwsRoleName:       aws.String("my-role"),
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			testName: "Malformed AWS account ID",
+			keyName:  "image-1/config.yml",
+			conf: repoConfig{
+				RepoName:        aws.String(repoName),
+				RepoTag:         aws.String(tagName),
+				TargetPlatforms: targetPlatforms,
+				Targets: []*Target{
+					{
+						AwsAccountId: aws.String("11111111111"),
+						AwsRegion:    aws.String(awsRegion),
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			testName: "Unknown registry type",
+			keyName:  "image-1/config.yml",
+			conf: repoConfig{
+				DefaultAwsAccountId: aws.String(awsAccountID),
+				DefaultRegion:       aws.String(awsRegion),
+				RepoName:            aws.String(repoName),
+				RepoTag:             aws.String(tagName),
+				TargetPlatforms:     targetPlatforms,
+				Targets: []*Target{
+					{
+						RegistryType: aws.String("unknown"),
+						AwsAccountId: aws.String(awsAccountID),
+						AwsRegion:    aws.String(awsRegion),
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			testName: "Only repo_tags set",
+			keyName:  "image-1/config.yml",
+			conf: repoConfig{
+				RepoName:        aws.String(repoName),
+				RepoTags:        []string{"alpine-3", "alpine-3.20"},
+				TargetPlatforms: targetPlatforms,
+				Targets:         []*Target{{AwsAccountId: aws.String(awsAccountID), AwsRegion: aws.String(awsRegion)}},
+			},
+			expectError: false,
+		},
+		{
+			testName: "Duplicate tag",
+			keyName:  "image-1/config.yml",
+			conf: repoConfig{
+				RepoName:        aws.String(repoName),
+				RepoTag:         aws.String("alpine-3"),
+				RepoTags:        []string{"alpine-3"},
+				TargetPlatforms: targetPlatforms,
+				Targets:         []*Target{{AwsAccountId: aws.String(awsAccountID), AwsRegion: aws.String(awsRegion)}},
+			},
+			expectError: true,
+		},
+		{
+			testName: "Empty repo_tags value",
+			keyName:  "image-1/config.yml",
+			conf: repoConfig{
+				RepoName:        aws.String(repoName),
+				RepoTags:        []string{""},
+				TargetPlatforms: targetPlatforms,
+				Targets:         []*Target{{AwsAccountId: aws.String(awsAccountID), AwsRegion: aws.String(awsRegion)}},
+			},
+			expectError: true,
+		},
+		{
+			testName: "Platforms only set on the targets",
+			keyName:  "image-1/config.yml",
+			conf: repoConfig{
+				RepoName: aws.String(repoName),
+				RepoTag:  aws.String(tagName),
+				Targets: []*Target{
+					{AwsAccountId: aws.String(awsAccountID), AwsRegion: aws.String(awsRegion), TargetPlatforms: []string{"linux/amd64"}},
+					{AwsAccountId: aws.String(awsAccountID), AwsRegion: aws.String("us-gov-west-1"), TargetPlatforms: targetPlatforms},
+				},
+			},
+			expectError: false,
+		},
+		{
+			testName: "Target without platforms and no repo level default",
+			keyName:  "image-1/config.yml",
+			conf: repoConfig{
+				RepoName: aws.String(repoName),
+				RepoTag:  aws.String(tagName),
+				Targets: []*Target{
+					{AwsAccountId: aws.String(awsAccountID), AwsRegion: aws.String(awsRegion), TargetPlatforms: []string{"linux/amd64"}},
+					{AwsAccountId: aws.String(awsAccountID), AwsRegion: aws.String(awsRegion)},
+				},
+			},
+			expectError: true,
+		},
+		{
+			testName: "Empty target build arg",
+			keyName:  "image-1/config.yml",
+			conf: repoConfig{
+				RepoName:        aws.String(repoName),
+				RepoTag:         aws.String(tagName),
+				TargetPlatforms: targetPlatforms,
+				Targets: []*Target{
+					{AwsAccountId: aws.String(awsAccountID), AwsRegion: aws.String(awsRegion), BuildArgs: map[string]string{"MIRROR": " "}},
+				},
+			},
+			expectError: true,
+		},
+		{
+			testName: "Empty target platform",
+			keyName:  "image-1/config.yml",
+			conf: repoConfig{
+				RepoName:        aws.String(repoName),
+				RepoTag:         aws.String(tagName),
+				TargetPlatforms: targetPlatforms,
+				Targets: []*Target{
+					{AwsAccountId: aws.String(awsAccountID), AwsRegion: aws.String(awsRegion), TargetPlatforms: []string{""}},
+				},
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tc := range cases {
@@ -244,12 +461,13 @@ func Test_validate(t *testing.T) {
 
 			c.repos[tc.keyName] = tc.conf
 
-			err := c.validate()
+			// The config is treated as a single file, so both its values and completeness are checked
+			problems := append(c.validateValues(tc.keyName, tc.conf), c.validateRepo(tc.keyName, tc.conf)...)
 
 			if tc.expectError {
-				require.Error(t, err)
+				require.NotEmpty(t, problems)
 			} else {
-				require.NoError(t, err)
+				require.Empty(t, problems)
 			}
 		})
 	}
@@ -282,6 +500,20 @@ func Test_addCalculatedFields(t *testing.T) {
 					AwsRegion:    aws.String(awsRegion),
 					AwsRoleName:  aws.String(iamRole),
 				},
+				{
+					RegistryType: aws.String(registryTypeOCI),
+					RegistryHost: aws.String("harbor.example.com"),
+				},
+				{
+					RegistryType:      aws.String(registryTypeGHCR),
+					RegistryNamespace: aws.String("my-org"),
+				},
+				{
+					RegistryType:      aws.String(registryTypeECRPublic),
+					RegistryNamespace: aws.String("my-alias"),
+					AwsAccountId:      aws.String(awsAccountID),
+					AwsRoleName:       aws.String(iamRole),
+				},
 			},
 		},
 	}
@@ -303,6 +535,70 @@ func Test_addCalculatedFields(t *testing.T) {
 	if len(p1.BuildArgs) > 0 {
 		require.NotEmpty(t, p1.Targets[0].BuildArgsStr)
 	}
+
+	require.Equal(t, registryTypeECR, *p1.Targets[0].RegistryType, "registry type should be resolved")
+
+	require.Equal(t, "harbor.example.com/repo-1:alpine", p1.Targets[1].FullImageRef)
+	require.Empty(t, p1.Targets[1].AWSRoleARN)
+
+	require.Equal(t, "ghcr.io/my-org/repo-1:alpine", p1.Targets[2].FullImageRef)
+
+	require.Equal(t, "public.ecr.aws/my-alias/repo-1:alpine", p1.Targets[3].FullImageRef)
+	require.Equal(t, p1.Targets[0].AWSRoleARN, p1.Targets[3].AWSRoleARN)
+}
+
+func Test_addCalculatedFields_multipleTags(t *testing.T) {
+	t.Parallel()
+
+	key := "path-one/config.yml"
+	c := config{repos: map[string]repoConfig{
+		key: {
+			RepoName: aws.String("repo-1"),
+			RepoTag:  aws.String("alpine-3"),
+			RepoTags: []string{"alpine-3.20"},
+			Targets:  []*Target{{RegistryType: aws.String(registryTypeGHCR), RegistryNamespace: aws.String("my-org")}},
+		},
+	}}
+	c.addCalculatedFields()
+
+	target := c.repos[key].Targets[0]
+	require.Equal(t, "ghcr.io/my-org/repo-1:alpine-3", target.FullImageRef, "the first tag should be the image ref")
+	require.Equal(t, []TagStatus{
+		{Tag: "alpine-3", FullImageRef: "ghcr.io/my-org/repo-1:alpine-3"},
+		{Tag: "alpine-3.20", FullImageRef: "ghcr.io/my-org/repo-1:alpine-3.20"},
+	}, target.Tags)
+	require.Equal(t, "-t ghcr.io/my-org/repo-1:alpine-3 -t ghcr.io/my-org/repo-1:alpine-3.20", target.TagArgsStr)
+}
+
+func Test_addCalculatedFields_targetOverrides(t *testing.T) {
+	t.Parallel()
+
+	key := "path-one/config.yml"
+	c := config{repos: map[string]repoConfig{
+		key: {
+			RepoName:        aws.String("repo-1"),
+			RepoTag:         aws.String("alpine"),
+			TargetPlatforms: []string{"linux/amd64", "linux/arm64"},
+			BuildArgs:       map[string]string{"MIRROR": "eu.example.com", "VERSION": "1"},
+			Targets: []*Target{
+				{AwsAccountId: aws.String("111111111111"), AwsRegion: aws.String("eu-west-1")},
+				{
+					AwsAccountId:    aws.String("222222222222"),
+					AwsRegion:       aws.String("us-gov-west-1"),
+					TargetPlatforms: []string{"linux/amd64"},
+					BuildArgs:       map[string]string{"MIRROR": "gov.example.com"},
+				},
+			},
+		},
+	}}
+	c.addCalculatedFields()
+
+	targets := c.repos[key].Targets
+	require.Equal(t, "linux/amd64,linux/arm64", targets[0].TargetPlatformStr)
+	require.Equal(t, "--build-arg MIRROR=eu.example.com --build-arg VERSION=1", targets[0].BuildArgsStr)
+	require.Equal(t, "linux/amd64", targets[1].TargetPlatformStr, "the target's platforms should replace the repo's")
+	require.Equal(t, "--build-arg MIRROR=gov.example.com --build-arg VERSION=1", targets[1].BuildArgsStr, "the target's build args should merge over the repo's")
+	require.Equal(t, map[string]string{"MIRROR": "eu.example.com", "VERSION": "1"}, c.repos[key].BuildArgs, "the repo build args must not be modified")
 }
 
 func Test_outputGitHubJSON(t *testing.T) {
@@ -428,7 +724,7 @@ func Test_readStrPointer(t *testing.T) {
 }
 
 func Test_parseYAMLFile(t *testing.T) {
-	result, err := parseYAMLFile("testdata/config-child.yml")
+	result, node, err := parseYAMLFile("testdata/config-child.yml", false)
 	require.NoError(t, err)
 	require.NotNil(t, result.RepoName)
 	require.NotNil(t, result.RepoTag)
@@ -441,7 +737,9 @@ func Test_parseYAMLFile(t *testing.T) {
 	require.NotNil(t, result.Targets[1].AwsRegion)
 	require.Equal(t, "ap-northeast-1", *result.Targets[1].AwsRegion)
 
-	_, err = parseYAMLFile("invalid-path.yml")
+	require.Equal(t, yaml.DocumentNode, node.Kind)
+
+	_, _, err = parseYAMLFile("invalid-path.yml", false)
 	require.Error(t, err)
 }
 
@@ -498,6 +796,23 @@ func Test_mergeRepoConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			testName: "Use the child's default AWS account ID",
+			defaultConf: &repoConfig{
+				DefaultRegion: aws.String(awsRegion),
+			},
+			childConf: &repoConfig{
+				RepoName:            aws.String(repoName),
+				RepoTag:             aws.String(tagName),
+				TargetPlatforms:     targetPlatforms,
+				DefaultAwsAccountId: aws.String(awsAccountID),
+				Targets: []*Target{
+					{
+						AwsRegion: aws.String(awsRegion),
+					},
+				},
+			},
+		},
 		{
 			testName: "Missing targets key use defaults",
 			defaultConf: &repoConfig{
@@ -546,7 +861,7 @@ func Test_parseChildConfig(t *testing.T) {
 	badImageDir := "testdata/bad-image-dir"
 	childConfigOneKey := fmt.Sprintf("%s/image-1/%s", imageDir, childConfigFile)
 	childConfigTwoKey := fmt.Sprintf("%s/image-2/%s", imageDir, childConfigFile)
-	c := config{repos: make(map[string]repoConfig)}
+	c := newConfig()
 	fullDefaultData := repoConfig{
 		DefaultAwsAccountId: aws.String("111111111111"),
 		DefaultRegion:       aws.String("eu-west-3"),
@@ -563,114 +878,88 @@ func Test_parseChildConfig(t *testing.T) {
 	require.Error(t, err)
 }
 
-type mockECRClient struct{}
+func Test_resolveRepoTags(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"image-1/Dockerfile": "FROM alpine:3", "image-2/Dockerfile": "FROM alpine:3"})
 
-func (m mockECRClient) ListImages(_ context.Context, input *ecr.ListImagesInput, _ ...func(*ecr.Options)) (*ecr.ListImagesOutput, error) {
-	var output ecr.ListImagesOutput
+	keyOne := dir + "/image-1/config.yml"
+	keyTwo := dir + "/image-2/config.yml"
+	keyThree := dir + "/image-3/config.yml"
 
-	if input.RepositoryName != nil {
-		if *input.RepositoryName == "repo-1" {
-			output = ecr.ListImagesOutput{
-				ImageIds: []ecrTypes.ImageIdentifier{
-					{ImageTag: aws.String("v1")},
-					{ImageTag: aws.String("v2")},
-				},
-			}
-		}
+	c := config{repos: map[string]repoConfig{
+		keyOne: {
+			RepoTag:         aws.String("alpine-3"),
+			RepoTagStrategy: aws.String(repoTagStrategyContentHash),
+		},
+		keyTwo: {
+			RepoTagStrategy: aws.String(repoTagStrategyContentHash),
+		},
+		keyThree: {
+			RepoTag: aws.String("static-tag"),
+		},
+	}}
 
-		if *input.RepositoryName == "repo-2" {
-			if input.NextToken != nil && *input.NextToken == "token" {
-				output = ecr.ListImagesOutput{
-					ImageIds: []ecrTypes.ImageIdentifier{
-						{ImageTag: aws.String("v2")},
-					},
-					NextToken: nil,
-				}
-				return &output, nil
-			}
-			output = ecr.ListImagesOutput{
-				ImageIds: []ecrTypes.ImageIdentifier{
-					{ImageTag: aws.String("v1")},
-				},
-				NextToken: aws.String("token"),
-			}
-		}
-	}
+	err := c.resolveRepoTags()
+	require.NoError(t, err)
 
-	return &output, nil
+	hashTag := *c.repos[keyTwo].RepoTag
+	require.Len(t, hashTag, contentHashLength)
+	require.Equal(t, "alpine-3-"+hashTag, *c.repos[keyOne].RepoTag, "repo_tag should be used as a prefix")
+	require.Equal(t, "static-tag", *c.repos[keyThree].RepoTag)
 }
 
-func Test_checkECRImageTags(t *testing.T) {
-	cases := []struct {
-		testName       string
-		keyName        string
-		conf           repoConfig
-		expectTagFound bool
-	}{
-		{
-			testName: "Docker tag found",
-			keyName:  "image-1/config.yml",
-			conf: repoConfig{
-				RepoName: aws.String("repo-1"),
-				RepoTag:  aws.String("v2"),
-				Targets: []*Target{
-					{
-						AwsAccountId: aws.String("1111111111"),
-						AwsRegion:    aws.String("eu-west-3"),
-					},
-				},
-			},
-			expectTagFound: true,
-		},
-		{
-			testName: "Docker tag missing",
-			keyName:  "image-1/config.yml",
-			conf: repoConfig{
-				RepoName: aws.String("repo-1"),
-				RepoTag:  aws.String("missing"),
-				Targets: []*Target{
-					{
-						AwsAccountId: aws.String("1111111111"),
-						AwsRegion:    aws.String("eu-west-3"),
-					},
-				},
-			},
-			expectTagFound: false,
+func Test_resolveRepoTags_multipleTags(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"image-1/Dockerfile": "FROM alpine:3"})
+	key := dir + "/image-1/config.yml"
+
+	c := config{repos: map[string]repoConfig{
+		key: {
+			RepoTags:        []string{"alpine-3", "alpine-3.20"},
+			RepoTagStrategy: aws.String(repoTagStrategyContentHash),
 		},
-		{
-			testName: "Paginated results",
-			keyName:  "image-2/config.yml",
-			conf: repoConfig{
-				RepoName: aws.String("repo-2"),
-				RepoTag:  aws.String("v2"),
+	}}
+
+	require.NoError(t, c.resolveRepoTags())
+
+	repo := c.repos[key]
+	require.Nil(t, repo.RepoTag)
+	require.Len(t, repo.RepoTags, 2)
+	hash := repo.RepoTags[0][len("alpine-3-"):]
+	require.Len(t, hash, contentHashLength)
+	require.Equal(t, []string{"alpine-3-" + hash, "alpine-3.20-" + hash}, repo.RepoTags, "each tag should be used as a prefix")
+}
+
+func Test_resolveRepoTags_targetBuildArgs(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"image-1/Dockerfile": "FROM alpine:3"})
+	key := dir + "/image-1/config.yml"
+
+	resolve := func(targetBuildArgs map[string]string) string {
+		c := config{repos: map[string]repoConfig{
+			key: {
+				RepoTagStrategy: aws.String(repoTagStrategyContentHash),
+				BuildArgs:       map[string]string{"MIRROR": "mirror.example.com"},
 				Targets: []*Target{
-					{
-						AwsAccountId: aws.String("1111111111"),
-						AwsRegion:    aws.String("eu-west-3"),
-					},
+					{AwsAccountId: aws.String("111111111111"), AwsRegion: aws.String("eu-west-1")},
+					{AwsAccountId: aws.String("111111111111"), AwsRegion: aws.String("us-gov-west-1"), BuildArgs: targetBuildArgs},
 				},
 			},
-			expectTagFound: true,
-		},
+		}}
+		require.NoError(t, c.resolveRepoTags())
+		return *c.repos[key].RepoTag
 	}
 
-	for _, tc := range cases {
-		t.Run(tc.testName, func(t *testing.T) {
-			t.Parallel()
-
-			c := config{
-				repos:     map[string]repoConfig{tc.keyName: tc.conf},
-				ecrClient: mockECRClient{},
-			}
-
-			err := c.checkECRImageTags(tc.keyName, 0, c.repos[tc.keyName], c.repos[tc.keyName].Targets[0])
-			require.NoError(t, err)
+	base := resolve(nil)
+	changed := resolve(map[string]string{"MIRROR": "gov.example.com"})
+	require.NotEqual(t, base, changed, "a target's build args should change the hash")
+	require.NotEqual(t, changed, resolve(map[string]string{"MIRROR": "other.example.com"}))
+	require.Equal(t, changed, resolve(map[string]string{"MIRROR": "gov.example.com"}))
+}
 
-			if tc.expectTagFound {
-				require.Equal(t, false, c.repos[tc.keyName].Targets[0].RemoteTagMissing)
-			} else {
-				require.Equal(t, true, c.repos[tc.keyName].Targets[0].RemoteTagMissing)
-			}
-		})
-	}
+func Test_repoConfig_tags(t *testing.T) {
+	require.Equal(t, []string{"alpine-3"}, repoConfig{RepoTag: aws.String("alpine-3")}.tags())
+	require.Equal(t, []string{"alpine-3.20", "alpine-3.20.1"}, repoConfig{RepoTags: []string{"alpine-3.20", "alpine-3.20.1"}}.tags())
+	require.Equal(t, []string{"alpine-3", "alpine-3.20"}, repoConfig{RepoTag: aws.String("alpine-3"), RepoTags: []string{"alpine-3.20"}}.tags())
+	require.Empty(t, repoConfig{}.tags())
 }
@@ -0,0 +1,226 @@
+package checker
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const (
+	schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+	// envReferencePattern matches a value referencing an environment variable, which is only known once expanded
+	envReferencePattern = `\$\{[^}]+\}`
+)
+
+// jsonSchema is the subset of JSON Schema used to describe the config files
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Const                string                 `json:"const,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MinProperties        *int                   `json:"minProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AllOf                []*jsonSchema          `json:"allOf,omitempty"`
+	AnyOf                []*jsonSchema          `json:"anyOf,omitempty"`
+	If                   *jsonSchema            `json:"if,omitempty"`
+	Then                 *jsonSchema            `json:"then,omitempty"`
+	Defs                 map[string]*jsonSchema `json:"$defs,omitempty"`
+}
+
+// schemaField holds the documentation and the validate rules of a config key which can't be derived from its type
+type schemaField struct {
+	description string
+	enum        []string
+	pattern     string
+
+	// nonEmpty requires strings, and the items and values of lists and maps, to have at least one character
+	nonEmpty bool
+
+	// environmentRefs allows a list of targets to reference environments by name
+	environmentRefs bool
+}
+
+// schemaFields describes every config key. Generating the schema fails if a new key is missing
+var schemaFields = map[string]schemaField{
+	"default_aws_account_id": {description: "AWS account ID used by ECR targets which don't set aws_account_id", pattern: awsAccountIDPattern.String()},
+	"default_aws_region":     {description: "AWS region used by ECR targets which don't set aws_region", nonEmpty: true},
+	"default_aws_role_name":  {description: "IAM role assumed by ECR targets which don't set aws_role_name", nonEmpty: true},
+	"repo_name":              {description: "Name of the image repository", nonEmpty: true},
+	"repo_tag":               {description: "Tag of the image, which can be a template such as {{ .BuildArgs.VERSION }}-{{ .GitShortSHA }}. Used as a prefix with the content-hash strategy", nonEmpty: true},
+	"repo_tags":              {description: "Additional tags of the image, each checked and pushed. Templates are supported as in repo_tag", nonEmpty: true},
+	"repo_tag_strategy":      {description: "How the tag is determined. content-hash derives it from the build inputs", enum: []string{repoTagStrategyStatic, repoTagStrategyContentHash}},
+	"target_platforms":       {description: "Platforms the image is built for, e.g. linux/amd64", nonEmpty: true},
+	"build_args":             {description: "Docker build args passed to the build", nonEmpty: true},
+	"ignore":                 {description: "Globs of image directories to skip, relative to this defaults file. Only valid in config-defaults.yml", nonEmpty: true},
+	"targets":                {description: "Registries the image is pushed to, or the names of environments defined in config-defaults.yml. Defaults to the AWS defaults if not set", environmentRefs: true},
+	"environments":           {description: "Named sets of targets which targets can reference by name. Only valid in config-defaults.yml"},
+	"aws_account_id":         {description: "AWS account ID of the ECR registry", pattern: awsAccountIDPattern.String()},
+	"aws_region":             {description: "AWS region of the ECR registry", nonEmpty: true},
+	"aws_role_name":          {description: "IAM role assumed to check the registry", nonEmpty: true},
+	"registry_type":          {description: "Type of registry. Defaults to ecr", enum: []string{registryTypeECR, registryTypeECRPublic, registryTypeOCI, registryTypeGHCR}},
+	"registry_host":          {description: "Host of an oci registry, e.g. harbor.example.com", nonEmpty: true},
+	"registry_namespace":     {description: "Namespace within the registry. The owner for ghcr and the registry alias for ecr-public", nonEmpty: true},
+	"registry_username":      {description: "Username used to authenticate with the registry", nonEmpty: true},
+	"registry_password_env":  {description: "Name of the environment variable holding the registry password or token", nonEmpty: true},
+	"registry_insecure":      {description: "Use plain HTTP to connect to an oci registry"},
+}
+
+// registryRequiredFields are the keys each registry type requires, mirroring validate
+var registryRequiredFields = map[string]string{
+	registryTypeOCI:       "registry_host",
+	registryTypeGHCR:      "registry_namespace",
+	registryTypeECRPublic: "registry_namespace",
+}
+
+// generateSchema builds the JSON Schema of config.yml and config-defaults.yml from the yaml tags of repoConfig
+func generateSchema() (*jsonSchema, error) {
+	target, err := structSchema(reflect.TypeFor[Target]())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, registryType := range slices.Sorted(maps.Keys(registryRequiredFields)) {
+		target.AllOf = append(target.AllOf, &jsonSchema{
+			If: &jsonSchema{
+				Properties: map[string]*jsonSchema{"registry_type": {Const: registryType}},
+				Required:   []string{"registry_type"},
+			},
+			Then: &jsonSchema{Required: []string{registryRequiredFields[registryType]}},
+		})
+	}
+
+	root, err := structSchema(reflect.TypeFor[repoConfig]())
+	if err != nil {
+		return nil, err
+	}
+
+	root.Schema = schemaDraft
+	root.Title = appName + " config"
+	root.Description = "Image config (config.yml) and the root defaults (config-defaults.yml)"
+	root.Defs = map[string]*jsonSchema{"target": target}
+
+	return root, nil
+}
+
+// structSchema describes a config struct as an object, using the yaml tags as the property names
+func structSchema(typ reflect.Type) (*jsonSchema, error) {
+	s := &jsonSchema{
+		Type:                 "object",
+		Properties:           make(map[string]*jsonSchema),
+		AdditionalProperties: false,
+	}
+
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		meta, ok := schemaFields[name]
+		if !ok {
+			return nil, fmt.Errorf("no schema description for %s.%s", typ.Name(), name)
+		}
+
+		prop, err := fieldSchema(field.Type, meta)
+		if err != nil {
+			return nil, fmt.Errorf("describing %s.%s: %w", typ.Name(), name, err)
+		}
+		prop.Description = meta.description
+
+		s.Properties[name] = prop
+	}
+
+	return s, nil
+}
+
+func fieldSchema(typ reflect.Type, meta schemaField) (*jsonSchema, error) {
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		s := &jsonSchema{Type: "string", Enum: meta.enum, Pattern: meta.pattern}
+		if meta.nonEmpty {
+			s.MinLength = intPtr(1)
+		}
+		if len(meta.enum) > 0 || meta.pattern != "" {
+			return allowEnvReference(s), nil
+		}
+		return s, nil
+	case reflect.Bool:
+		return allowEnvReference(&jsonSchema{Type: "boolean"}), nil
+	case reflect.Slice:
+		if typ.Elem() == reflect.TypeFor[*Target]() {
+			items := &jsonSchema{Ref: "#/$defs/target"}
+			if meta.environmentRefs {
+				items = &jsonSchema{AnyOf: []*jsonSchema{items, {Type: "string", MinLength: intPtr(1)}}}
+			}
+			return &jsonSchema{Type: "array", Items: items}, nil
+		}
+
+		items, err := fieldSchema(typ.Elem(), meta)
+		if err != nil {
+			return nil, err
+		}
+		s := &jsonSchema{Type: "array", Items: items}
+		if meta.nonEmpty {
+			s.MinItems = intPtr(1)
+		}
+		return s, nil
+	case reflect.Map:
+		values, err := fieldSchema(typ.Elem(), meta)
+		if err != nil {
+			return nil, err
+		}
+		if meta.nonEmpty {
+			// Values must contain more than whitespace
+			values.Pattern = `\S`
+		}
+		s := &jsonSchema{Type: "object", AdditionalProperties: values}
+		if meta.nonEmpty {
+			s.MinProperties = intPtr(1)
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", typ)
+	}
+}
+
+// allowEnvReference also accepts a ${VAR} reference for a value whose schema would otherwise reject one, as the value
+// is only checked once the environment variables have been expanded
+func allowEnvReference(s *jsonSchema) *jsonSchema {
+	return &jsonSchema{AnyOf: []*jsonSchema{s, {Type: "string", Pattern: envReferencePattern}}}
+}
+
+// Schema outputs the JSON Schema of the config files, for editor autocomplete and validation
+func Schema(opts Options) error {
+	if _, err := opts.format(FormatJSON); err != nil {
+		return err
+	}
+
+	s, err := generateSchema()
+	if err != nil {
+		return fmt.Errorf("generating schema: %w", err)
+	}
+
+	return writeJSON(opts.output(), s)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
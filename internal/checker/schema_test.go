@@ -0,0 +1,67 @@
+package checker
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// committedSchema is the published schema referenced by editors
+const committedSchema = "../../schema/config.schema.json"
+
+func Test_Schema_inSync(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, Schema(Options{Output: &out}))
+
+	committed, err := os.ReadFile(committedSchema)
+	require.NoError(t, err)
+	require.Equal(t, string(committed), out.String(), "schema is out of date, regenerate it with: go run . schema > schema/config.schema.json")
+}
+
+func Test_generateSchema(t *testing.T) {
+	s, err := generateSchema()
+	require.NoError(t, err)
+
+	require.Equal(t, false, s.AdditionalProperties, "unknown keys are rejected")
+
+	platforms := s.Properties["target_platforms"]
+	require.Equal(t, 1, *platforms.MinItems)
+	require.Equal(t, 1, *platforms.Items.MinLength)
+
+	buildArgs := s.Properties["build_args"].AdditionalProperties.(*jsonSchema)
+	require.Equal(t, `\S`, buildArgs.Pattern)
+
+	targets := s.Properties["targets"].Items.AnyOf
+	require.Equal(t, "#/$defs/target", targets[0].Ref)
+	require.Equal(t, "string", targets[1].Type, "targets can reference environments by name")
+	require.Equal(t, "#/$defs/target", s.Properties["environments"].AdditionalProperties.(*jsonSchema).Items.Ref)
+	target := s.Defs["target"]
+	require.Equal(t, awsAccountIDPattern.String(), target.Properties["aws_account_id"].AnyOf[0].Pattern)
+	require.ElementsMatch(t, []string{registryTypeECR, registryTypeECRPublic, registryTypeOCI, registryTypeGHCR}, target.Properties["registry_type"].AnyOf[0].Enum)
+
+	// Values which are only valid once expanded can reference environment variables
+	for _, prop := range []*jsonSchema{s.Properties["default_aws_account_id"], target.Properties["aws_account_id"], target.Properties["registry_insecure"]} {
+		require.Len(t, prop.AnyOf, 2)
+		require.Equal(t, envReferencePattern, prop.AnyOf[1].Pattern)
+		require.Regexp(t, prop.AnyOf[1].Pattern, "${AWS_ACCOUNT_ID}")
+	}
+	require.Equal(t, "boolean", target.Properties["registry_insecure"].AnyOf[0].Type)
+	require.Len(t, target.AllOf, len(registryRequiredFields))
+	require.NotContains(t, target.Properties, "full_image_ref", "calculated fields are not config")
+
+	_, err = json.Marshal(s)
+	require.NoError(t, err)
+}
+
+func Test_fieldSchema_unsupported(t *testing.T) {
+	type unsupported struct {
+		Count int `yaml:"repo_name"`
+	}
+
+	_, err := structSchema(reflect.TypeFor[unsupported]())
+	require.Error(t, err)
+}
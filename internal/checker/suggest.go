@@ -0,0 +1,96 @@
+package checker
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// unknownFieldError matches the error yaml.v3 returns for unknown keys when decoding strictly
+var unknownFieldError = regexp.MustCompile(`^field (\S+) not found in type (\S+)$`)
+
+// configTypes are the types decoded from the config files, keyed by the type name used in yaml.v3 errors
+var configTypes = map[string]reflect.Type{
+	reflect.TypeFor[repoConfig]().String():  reflect.TypeFor[repoConfig](),
+	reflect.TypeFor[Target]().String():      reflect.TypeFor[Target](),
+	reflect.TypeFor[plainTarget]().String(): reflect.TypeFor[Target](),
+}
+
+// describeUnknownField rewrites an unknown key error with a suggestion of the closest known key, if there is one.
+// Other messages are returned as-is
+func describeUnknownField(msg string) string {
+	m := unknownFieldError.FindStringSubmatch(msg)
+	if m == nil {
+		return msg
+	}
+
+	field, typ := m[1], configTypes[m[2]]
+	if typ == nil {
+		return fmt.Sprintf("unknown key %s", field)
+	}
+
+	if suggestion := closestField(field, yamlFields(typ)); suggestion != "" {
+		return fmt.Sprintf("unknown key %s, did you mean %s?", field, suggestion)
+	}
+
+	return fmt.Sprintf("unknown key %s", field)
+}
+
+// yamlFields returns the YAML keys of the struct's fields, skipping those not decoded from YAML
+func yamlFields(typ reflect.Type) []string {
+	fields := make([]string, 0, typ.NumField())
+
+	for i := range typ.NumField() {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, name)
+	}
+
+	return fields
+}
+
+// closestField returns the known field with the smallest edit distance from the unknown one, if it is close enough
+// to be a likely typo
+func closestField(field string, known []string) string {
+	best, bestDistance := "", -1
+
+	for _, candidate := range known {
+		d := editDistance(field, candidate)
+		if bestDistance == -1 || d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+
+	// Allow roughly one mistake per three characters, so short keys need to be close
+	if bestDistance == -1 || bestDistance > max(2, len(field)/3) {
+		return ""
+	}
+
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
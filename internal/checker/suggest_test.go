@@ -0,0 +1,79 @@
+package checker
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_describeUnknownField(t *testing.T) {
+	cases := []struct {
+		testName string
+		msg      string
+		expected string
+	}{
+		{
+			testName: "Typo in repo config",
+			msg:      "field target_platform not found in type checker.repoConfig",
+			expected: "unknown key target_platform, did you mean target_platforms?",
+		},
+		{
+			testName: "Typo in target",
+			msg:      "field aws_acount_id not found in type checker.Target",
+			expected: "unknown key aws_acount_id, did you mean aws_account_id?",
+		},
+		{
+			testName: "Calculated fields are not suggested",
+			msg:      "field full_image_ref not found in type checker.Target",
+			expected: "unknown key full_image_ref",
+		},
+		{
+			testName: "Nothing close",
+			msg:      "field completely_different not found in type checker.repoConfig",
+			expected: "unknown key completely_different",
+		},
+		{
+			testName: "Other errors are unchanged",
+			msg:      "cannot unmarshal !!str `linux` into []string",
+			expected: "cannot unmarshal !!str `linux` into []string",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.expected, describeUnknownField(tc.msg))
+		})
+	}
+}
+
+func Test_editDistance(t *testing.T) {
+	require.Equal(t, 0, editDistance("build_args", "build_args"))
+	require.Equal(t, 1, editDistance("build_arg", "build_args"))
+	require.Equal(t, 2, editDistance("repo_tga", "repo_tag"))
+	require.Equal(t, 3, editDistance("", "abc"))
+}
+
+func Test_parseYAMLFile_unknownFields(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.yml": "repo_name: image-1\nbuild_arg:\n  A: b\ntargets:\n  - aws_regoin: eu-west-1\n",
+	})
+	path := filepath.Join(dir, "config.yml")
+
+	_, _, err := parseYAMLFile(path, false)
+
+	var problems ValidationErrors
+	require.True(t, errors.As(err, &problems))
+	require.Equal(t, ValidationErrors{
+		{Path: path, Line: 2, Message: "unknown key build_arg, did you mean build_args?"},
+		{Path: path, Line: 5, Message: "unknown key aws_regoin, did you mean aws_region?"},
+	}, problems)
+
+	result, _, err := parseYAMLFile(path, true)
+	require.NoError(t, err, "unknown keys should be ignored when allowed")
+	require.Equal(t, "image-1", *result.RepoName)
+}
@@ -0,0 +1,141 @@
+package checker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// shortSHALength is the length of GitShortSHA, fixed so tags don't change as the repo grows
+const shortSHALength = 7
+
+// tagTemplateData is what repo_tag and repo_tags templates are rendered with, e.g. {{ .BuildArgs.VERSION }}
+type tagTemplateData struct {
+	BuildArgs map[string]string
+	Env       map[string]string
+	RepoName  string
+
+	// Dir is the name of the image directory
+	Dir string
+
+	imageDir string
+	sha      string
+	branch   string
+}
+
+// GitSHA returns the commit checked out in the image directory. Git is only run if a template uses it
+func (d *tagTemplateData) GitSHA() (string, error) {
+	if d.sha == "" {
+		out, err := runGit(d.imageDir, "rev-parse", "HEAD")
+		if err != nil {
+			return "", fmt.Errorf("finding git commit: %w", err)
+		}
+		d.sha = strings.TrimSpace(out)
+	}
+
+	return d.sha, nil
+}
+
+// GitShortSHA returns the abbreviated commit checked out in the image directory
+func (d *tagTemplateData) GitShortSHA() (string, error) {
+	sha, err := d.GitSHA()
+	if err != nil {
+		return "", err
+	}
+
+	return sha[:min(shortSHALength, len(sha))], nil
+}
+
+// GitBranch returns the current branch, with slashes replaced as they aren't valid in a tag. A detached HEAD, as in
+// pull request CI, is returned as HEAD
+func (d *tagTemplateData) GitBranch() (string, error) {
+	if d.branch == "" {
+		out, err := runGit(d.imageDir, "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			return "", fmt.Errorf("finding git branch: %w", err)
+		}
+		d.branch = strings.ReplaceAll(strings.TrimSpace(out), "/", "-")
+	}
+
+	return d.branch, nil
+}
+
+// renderTagTemplates renders the repo_tag and repo_tags templates of each repo, returning the problems found as
+// ValidationErrors. Tags without a template action are left as they are
+func (c *config) renderTagTemplates() error {
+	problems := make(ValidationErrors, 0)
+	env := environMap()
+
+	for _, key := range sortedKeys(c.repos) {
+		repo := c.repos[key]
+		data := &tagTemplateData{
+			BuildArgs: repo.BuildArgs,
+			Env:       env,
+			RepoName:  readStrPointer(repo.RepoName),
+			Dir:       filepath.Base(filepath.Dir(key)),
+			imageDir:  filepath.Dir(key),
+		}
+
+		if !strPtrEmpty(repo.RepoTag) {
+			tag, err := renderTagTemplate("repo_tag", *repo.RepoTag, data)
+			if err != nil {
+				problems = append(problems, c.errorAt(key, err.Error(), "repo_tag"))
+			} else {
+				repo.RepoTag = &tag
+			}
+		}
+
+		tags := make([]string, 0, len(repo.RepoTags))
+		for idx, t := range repo.RepoTags {
+			tag, err := renderTagTemplate("repo_tags", t, data)
+			if err != nil {
+				problems = append(problems, c.errorAt(key, fmt.Sprintf("%s, index %d", err, idx), "repo_tags", idx))
+			}
+			tags = append(tags, tag)
+		}
+		if repo.RepoTags != nil {
+			repo.RepoTags = tags
+		}
+
+		c.repos[key] = repo
+	}
+
+	return problems.orNil()
+}
+
+// renderTagTemplate renders a single tag. Referencing a missing build arg or environment variable is an error rather
+// than rendering as an empty string
+func renderTagTemplate(name, tag string, data *tagTemplateData) (string, error) {
+	if !strings.Contains(tag, "{{") {
+		return tag, nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(tag)
+	if err != nil {
+		return tag, fmt.Errorf("parsing %s template: %w", name, err)
+	}
+
+	var sb strings.Builder
+	if err = tmpl.Execute(&sb, data); err != nil {
+		return tag, fmt.Errorf("rendering %s template: %w", name, err)
+	}
+
+	if strings.TrimSpace(sb.String()) == "" {
+		return tag, fmt.Errorf("%s template %q rendered an empty tag", name, tag)
+	}
+
+	return sb.String(), nil
+}
+
+func environMap() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+
+	return env
+}
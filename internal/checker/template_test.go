@@ -0,0 +1,112 @@
+package checker
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_renderTagTemplate(t *testing.T) {
+	t.Setenv("TAG_TEMPLATE_TEST", "from-env")
+
+	data := &tagTemplateData{
+		BuildArgs: map[string]string{"BASE_IMAGE_TAG": "3.20"},
+		Env:       environMap(),
+		RepoName:  "repo-1",
+		Dir:       "image-1",
+		sha:       "0123456789abcdef0123456789abcdef01234567",
+		branch:    "feature-x",
+	}
+
+	cases := []struct {
+		testName    string
+		tag         string
+		expected    string
+		expectError bool
+	}{
+		{testName: "Literal tag", tag: "alpine-3", expected: "alpine-3"},
+		{testName: "Build arg and short SHA", tag: "{{ .BuildArgs.BASE_IMAGE_TAG }}-{{ .GitShortSHA }}", expected: "3.20-0123456"},
+		{testName: "Full SHA", tag: "{{ .GitSHA }}", expected: "0123456789abcdef0123456789abcdef01234567"},
+		{testName: "Branch", tag: "{{ .GitBranch }}", expected: "feature-x"},
+		{testName: "Env var", tag: "{{ .Env.TAG_TEMPLATE_TEST }}", expected: "from-env"},
+		{testName: "Directory and repo name", tag: "{{ .RepoName }}-{{ .Dir }}", expected: "repo-1-image-1"},
+		{testName: "Missing build arg", tag: "{{ .BuildArgs.MISSING }}", expectError: true},
+		{testName: "Missing env var", tag: "{{ .Env.TAG_TEMPLATE_TEST_MISSING }}", expectError: true},
+		{testName: "Unknown field", tag: "{{ .Unknown }}", expectError: true},
+		{testName: "Syntax error", tag: "{{ .Dir", expectError: true},
+		{testName: "Empty result", tag: "{{ if false }}x{{ end }}", expectError: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := renderTagTemplate("repo_tag", tc.tag, data)
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func Test_tagTemplateData_git(t *testing.T) {
+	dir := newTestGitRepo(t, map[string]string{"image-1/Dockerfile": "FROM alpine:3"})
+	_, err := runGit(dir, "checkout", "--quiet", "-b", "feature/tags")
+	require.NoError(t, err)
+
+	out, err := runGit(dir, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	sha := strings.TrimSpace(out)
+
+	data := &tagTemplateData{imageDir: filepath.Join(dir, "image-1")}
+
+	result, err := data.GitSHA()
+	require.NoError(t, err)
+	require.Equal(t, sha, result)
+
+	result, err = data.GitShortSHA()
+	require.NoError(t, err)
+	require.Equal(t, sha[:shortSHALength], result)
+
+	result, err = data.GitBranch()
+	require.NoError(t, err)
+	require.Equal(t, "feature-tags", result, "slashes aren't valid in a tag")
+
+	_, err = (&tagTemplateData{imageDir: t.TempDir()}).GitSHA()
+	require.Error(t, err, "outside of a git repo")
+}
+
+func Test_renderTagTemplates(t *testing.T) {
+	keyOne := "images/image-1/config.yml"
+	keyTwo := "images/image-2/config.yml"
+
+	c := newConfig()
+	c.repos[keyOne] = repoConfig{
+		RepoName:  aws.String("repo-1"),
+		RepoTag:   aws.String("{{ .BuildArgs.VERSION }}"),
+		RepoTags:  []string{"{{ .Dir }}-{{ .BuildArgs.VERSION }}", "latest"},
+		BuildArgs: map[string]string{"VERSION": "1.2.3"},
+	}
+	c.repos[keyTwo] = repoConfig{
+		RepoName: aws.String("repo-2"),
+		RepoTags: []string{"ok", "{{ .BuildArgs.VERSION }}"},
+	}
+
+	err := c.renderTagTemplates()
+
+	var problems ValidationErrors
+	require.ErrorAs(t, err, &problems)
+	require.Len(t, problems, 1)
+	require.Equal(t, keyTwo, problems[0].Path)
+	require.Contains(t, problems[0].Message, "index 1")
+
+	require.Equal(t, "1.2.3", *c.repos[keyOne].RepoTag)
+	require.Equal(t, []string{"image-1-1.2.3", "latest"}, c.repos[keyOne].RepoTags)
+}
@@ -0,0 +1,106 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// undeployedChange is a target of a changed image directory whose repo tag already exists, so the change would never
+// be built
+type undeployedChange struct {
+	ConfigPath string `json:"config_path"`
+	Target     Target `json:"target"`
+}
+
+// Verify reports the images whose directory has changed since the since ref but whose repo tag already exists in
+// every target. These changes are dropped by the missing tag filter, so would silently never be deployed
+func Verify(opts Options) error {
+	format, err := opts.format(FormatText, FormatJSON)
+	if err != nil {
+		return err
+	}
+
+	if opts.SinceRef == "" {
+		return fmt.Errorf("a since ref is required to verify changed images")
+	}
+
+	c, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	if err = c.checkRegistries(context.Background(), opts.Concurrency); err != nil {
+		return err
+	}
+
+	changes := findUndeployedChanges(c.repos)
+	if format == FormatJSON {
+		if err = writeJSON(opts.output(), changes); err != nil {
+			return err
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if format == FormatText {
+		if err = writeUndeployedChanges(opts.output(), changes); err != nil {
+			return fmt.Errorf("writing report: %w", err)
+		}
+	}
+
+	return fmt.Errorf("%d changed image(s) already have their repo tag in every target, bump repo_tag to deploy the change", countConfigs(changes))
+}
+
+// findUndeployedChanges returns every target of the repos which aren't missing their tag in any target.
+// The repos must already be filtered to those which have changed
+func findUndeployedChanges(repos map[string]repoConfig) []undeployedChange {
+	changes := make([]undeployedChange, 0)
+
+	for _, key := range sortedKeys(repos) {
+		repo := repos[key]
+		if len(repo.Targets) == 0 {
+			continue
+		}
+
+		// A single missing target means the change will be built and deployed somewhere
+		deployed := false
+		for _, target := range repo.Targets {
+			if target.RemoteTagMissing {
+				deployed = true
+				break
+			}
+		}
+		if deployed {
+			continue
+		}
+
+		for _, target := range repo.Targets {
+			changes = append(changes, undeployedChange{ConfigPath: key, Target: *target})
+		}
+	}
+
+	return changes
+}
+
+func writeUndeployedChanges(w io.Writer, changes []undeployedChange) error {
+	var sb strings.Builder
+	sb.WriteString("Changed images whose repo tag already exists in every target:\n")
+	for _, change := range changes {
+		_, _ = fmt.Fprintf(&sb, "  %s: %s\n", change.ConfigPath, change.Target.FullImageRef)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func countConfigs(changes []undeployedChange) int {
+	configs := make(map[string]struct{})
+	for _, change := range changes {
+		configs[change.ConfigPath] = struct{}{}
+	}
+	return len(configs)
+}
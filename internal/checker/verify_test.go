@@ -0,0 +1,126 @@
+package checker
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_findUndeployedChanges(t *testing.T) {
+	repos := map[string]repoConfig{
+		"images/image-1/config.yml": {
+			RepoName: aws.String("image-1"),
+			Targets: []*Target{
+				{FullImageRef: "11111111111.dkr.ecr.eu-west-1.amazonaws.com/image-1:1"},
+				{FullImageRef: "22222222222.dkr.ecr.eu-west-1.amazonaws.com/image-1:1"},
+			},
+		},
+		"images/image-2/config.yml": {
+			RepoName: aws.String("image-2"),
+			Targets: []*Target{
+				{FullImageRef: "11111111111.dkr.ecr.eu-west-1.amazonaws.com/image-2:1"},
+				{FullImageRef: "22222222222.dkr.ecr.eu-west-1.amazonaws.com/image-2:1", RemoteTagMissing: true},
+			},
+		},
+		"images/image-3/config.yml": {
+			RepoName: aws.String("image-3"),
+			Targets:  []*Target{{FullImageRef: "11111111111.dkr.ecr.eu-west-1.amazonaws.com/image-3:1", RemoteTagMissing: true}},
+		},
+		"images/image-4/config.yml": {
+			RepoName: aws.String("image-4"),
+		},
+	}
+
+	changes := findUndeployedChanges(repos)
+	require.Len(t, changes, 2)
+	for _, change := range changes {
+		require.Equal(t, "images/image-1/config.yml", change.ConfigPath)
+	}
+	require.Equal(t, 1, countConfigs(changes))
+
+	var out bytes.Buffer
+	require.NoError(t, writeUndeployedChanges(&out, changes))
+	require.Equal(t, "Changed images whose repo tag already exists in every target:\n"+
+		"  images/image-1/config.yml: 11111111111.dkr.ecr.eu-west-1.amazonaws.com/image-1:1\n"+
+		"  images/image-1/config.yml: 22222222222.dkr.ecr.eu-west-1.amazonaws.com/image-1:1\n", out.String())
+
+	require.Empty(t, findUndeployedChanges(nil))
+}
+
+func Test_Verify_requiresSinceRef(t *testing.T) {
+	err := Verify(Options{ImageDirectory: t.TempDir(), Concurrency: DefaultConcurrency})
+	require.Error(t, err)
+}
+
+func Test_Verify(t *testing.T) {
+	// The fake registry is used for every target, so AWS is connected to without any credentials or shared config
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("AWS_PROFILE", "")
+
+	dir := newTestGitRepo(t, map[string]string{
+		"config-defaults.yml":       "default_aws_account_id: \"111111111111\"\ndefault_aws_region: eu-west-1\n",
+		"images/image-1/Dockerfile": "FROM alpine:3",
+		"images/image-1/config.yml": "repo_name: image-1\nrepo_tag: \"1\"\ntarget_platforms: [linux/amd64]\n",
+		"images/image-2/Dockerfile": "FROM alpine:3",
+		"images/image-2/config.yml": "repo_name: image-2\nrepo_tag: \"1\"\ntarget_platforms: [linux/amd64]\n",
+		"images/image-3/Dockerfile": "FROM alpine:3",
+		"images/image-3/config.yml": "repo_name: image-3\nrepo_tag: \"1\"\ntarget_platforms: [linux/amd64]\n",
+	})
+
+	_, err := runGit(dir, "branch", "base")
+	require.NoError(t, err)
+
+	// image-1 changes without bumping its tag, image-2 bumps its tag and image-3 is unchanged
+	writeFiles(t, dir, map[string]string{
+		"images/image-1/Dockerfile": "FROM alpine:3.20",
+		"images/image-2/Dockerfile": "FROM alpine:3.20",
+		"images/image-2/config.yml": "repo_name: image-2\nrepo_tag: \"2\"\ntarget_platforms: [linux/amd64]\n",
+	})
+	commitAll(t, dir, "change images")
+
+	registry := newFakeRegistry()
+	for _, repoName := range []string{"image-1", "image-2", "image-3"} {
+		registry.addImage(repoName, "1", Manifest{MediaType: mediaTypeOCIIndex, Platforms: []string{"linux/amd64"}})
+	}
+
+	var out bytes.Buffer
+	opts := Options{
+		ImageDirectory: filepath.Join(dir, "images"),
+		Concurrency:    DefaultConcurrency,
+		SinceRef:       "base",
+		Output:         &out,
+		newRegistry: func(_ Target, _ string) (Registry, error) {
+			return registry, nil
+		},
+	}
+
+	err = Verify(opts)
+	require.EqualError(t, err, "1 changed image(s) already have their repo tag in every target, bump repo_tag to deploy the change")
+
+	key := filepath.Join(opts.ImageDirectory, "image-1", childConfigFile)
+	require.Equal(t, "Changed images whose repo tag already exists in every target:\n"+
+		"  "+key+": 111111111111.dkr.ecr.eu-west-1.amazonaws.com/image-1:1\n", out.String())
+
+	out.Reset()
+	opts.Format = FormatJSON
+	require.Error(t, Verify(opts))
+
+	var changes []undeployedChange
+	require.NoError(t, json.Unmarshal(out.Bytes(), &changes))
+	require.Len(t, changes, 1)
+	require.Equal(t, key, changes[0].ConfigPath)
+	require.Equal(t, "111111111111.dkr.ecr.eu-west-1.amazonaws.com/image-1:1", changes[0].Target.FullImageRef)
+
+	// Once the tag is bumped the change will be deployed
+	writeFiles(t, dir, map[string]string{"images/image-1/config.yml": "repo_name: image-1\nrepo_tag: \"3\"\ntarget_platforms: [linux/amd64]\n"})
+	commitAll(t, dir, "bump image-1")
+
+	out.Reset()
+	require.NoError(t, Verify(opts))
+	require.JSONEq(t, "[]", out.String())
+}